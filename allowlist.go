@@ -0,0 +1,21 @@
+package bot_lambda
+
+import (
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// WithAllowedApplicationIDs rejects interactions from applications other than those provided with a 403, e.g. when
+// several applications are routed to one endpoint. Interactions from all applications are allowed if none are
+// provided.
+func WithAllowedApplicationIDs(ids ...string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.allowedApplicationIDs = ids
+	}
+}
+
+// applicationAllowed returns true if the interaction was sent to an allowed application
+func (e *Endpoint) applicationAllowed(i *discordgo.InteractionCreate) bool {
+	return len(e.allowedApplicationIDs) == 0 || slices.Contains(e.allowedApplicationIDs, i.AppID)
+}
@@ -0,0 +1,35 @@
+package bot_lambda
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAllowedApplicationIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		appID   string
+		code    int
+		calls   int
+	}{
+		{name: "allow all", allowed: nil, appID: "1234", code: http.StatusAccepted, calls: 1},
+		{name: "allowed", allowed: []string{"1234", "5678"}, appID: "5678", code: http.StatusAccepted, calls: 1},
+		{name: "disallowed", allowed: []string{"1234"}, appID: "5678", code: http.StatusForbidden, calls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, calls := commandEndpoint(t, WithAllowedApplicationIDs(tt.allowed...))
+
+			i := fooCommand()
+			i.AppID = tt.appID
+			res := send(t, e, i)
+
+			assert.Equal(t, tt.code, res.StatusCode)
+			assert.Equal(t, tt.calls, *calls)
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package bot_lambda
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -116,3 +119,162 @@ func TestEndpoint_ApplicationCommandWithDeferredResponse(t *testing.T) {
 	// then the handler should have been called n times
 	assert.Equal(t, 1, calls)
 }
+
+func TestEndpoint_ApplicationCommandWithCustomDeferredResponse(t *testing.T) {
+	// given an endpoint with a custom deferred response
+	l := slogt.New(t)
+	e := New(
+		nil,
+		WithLogger(l),
+		WithRouter(router.New(router.WithLogger(l))),
+		WithDeferredResponse(&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Loading...",
+			},
+		}),
+	)
+
+	// given the endpoint has application command foo
+	e.WithMessageApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return nil
+	})
+
+	// the interaction response endpoint expects the custom deferred response
+	var received *discordgo.InteractionResponse
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	t.Cleanup(server.Close)
+	fakediscord.Configure(server.URL + "/")
+
+	// when the endpoint receives the interaction
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				ID:    "interaction_id",
+				Type:  discordgo.InteractionApplicationCommand,
+				Token: "interaction_token",
+				Data: discordgo.ApplicationCommandInteractionData{
+					Name:        "foo",
+					CommandType: discordgo.MessageApplicationCommand,
+				},
+			},
+		})),
+	})
+
+	// then the custom deferred response should have been sent
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.NotNil(t, received)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, received.Type)
+	assert.Equal(t, "Loading...", received.Data.Content)
+}
+
+func TestWithDeferredResponse_InvalidType(t *testing.T) {
+	for _, res := range []*discordgo.InteractionResponse{
+		nil,
+		{Type: discordgo.InteractionResponseChannelMessageWithSource},
+		{Type: discordgo.InteractionResponseDeferredMessageUpdate},
+	} {
+		// given an endpoint with a deferred response which cannot defer commands
+		buf := &bytes.Buffer{}
+		e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithDeferredResponse(res))
+
+		// then the error should be logged and the default deferred response used
+		assert.NotNil(t, findRecord(logRecords(t, buf), "Deferred response must be a deferred channel message, using the default deferred response"))
+		assert.Equal(t, defaultDeferredResponse(), e.deferredResponse)
+		assert.True(t, e.deferredResponseEnabled)
+	}
+}
+
+func TestEndpoint_WithHTTPClient(t *testing.T) {
+	// the interaction response endpoint expects a request
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v9/interactions/interaction_id/interaction_token/callback", r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// given an endpoint with a client which routes requests to the server
+	l := slogt.New(t)
+	e := New(
+		nil,
+		WithLogger(l),
+		WithDeferredResponseEnabled(true),
+		WithHTTPClient(&http.Client{Transport: rewriteTransport{target: target}}),
+	)
+	e.WithMessageApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return nil
+	})
+
+	// when the endpoint receives the interaction
+	res := send(t, e, &discordgo.Interaction{
+		ID:    "interaction_id",
+		Type:  discordgo.InteractionApplicationCommand,
+		Token: "interaction_token",
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        "foo",
+			CommandType: discordgo.MessageApplicationCommand,
+		},
+	})
+
+	// then the deferred response should have been sent via the client
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+// rewriteTransport sends all requests to the target host
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestDeferredResponse_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "401: Unauthorized", "code": 0}`))
+	}))
+	t.Cleanup(server.Close)
+
+	buf := &bytes.Buffer{}
+	metrics := countingMetrics{}
+	e, calls := commandEndpoint(t,
+		WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		WithMetrics(metrics),
+		WithDeferredResponseEnabled(true),
+		WithDiscordEndpoint(server.URL),
+	)
+
+	// when the deferred response is rejected by discord
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+
+	// then the invocation should fail without handling the command
+	require.Error(t, err)
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, int64(1), metrics[MetricDeferredResponseFailed])
+
+	// and the callback response should be logged
+	r := findRecord(logRecords(t, buf), "Deferred response rejected")
+	require.NotNil(t, r)
+	assert.EqualValues(t, http.StatusUnauthorized, r["status"])
+	assert.Contains(t, r["body"], "401: Unauthorized")
+}
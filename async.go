@@ -0,0 +1,190 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/sessionprovider"
+)
+
+// SQSClient sends messages to an SQS queue. Implement it with an adapter around the AWS SDK client, e.g.
+//
+//	func (c adapter) SendMessage(ctx context.Context, queueURL, body string) error {
+//		_, err := c.client.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: &queueURL, MessageBody: &body})
+//		return err
+//	}
+type SQSClient interface {
+	SendMessage(ctx context.Context, queueURL, body string) error
+}
+
+type deferQueue struct {
+	url    string
+	client SQSClient
+}
+
+// WithDeferToSQS sends a deferred response for each application command and then enqueues the interaction (including
+// its token) to the queue instead of routing it, so it can be processed by a separate function using HandleSQS.
+// This keeps the acknowledging function well within Discord's initial response deadline.
+func WithDeferToSQS(queueURL string, client SQSClient) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.deferredResponseEnabled = true
+		endpoint.deferQueue = &deferQueue{url: queueURL, client: client}
+	}
+}
+
+type requestBodyKey struct{}
+
+// withRequestBody adds the verified request body to the context, so that it can be enqueued as received
+func withRequestBody(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, requestBodyKey{}, body)
+}
+
+// enqueue sends the interaction to the defer queue. The request body is enqueued rather than the decoded interaction,
+// as discordgo.Interaction does not include fields such as the entitlements and installation context.
+func (e *Endpoint) enqueue(ctx context.Context, i *discordgo.InteractionCreate) (err error) {
+	ctx, seg := e.startSpan(ctx, "enqueue")
+	defer func() { seg.End(err) }()
+
+	bs, ok := ctx.Value(requestBodyKey{}).([]byte)
+	if !ok {
+		if bs, err = json.Marshal(i); err != nil {
+			return fmt.Errorf("marshal interaction: %w", err)
+		}
+	}
+
+	if err = e.deferQueue.client.SendMessage(ctx, e.deferQueue.url, string(bs)); err != nil {
+		return fmt.Errorf("enqueue interaction: %w", err)
+	}
+
+	return nil
+}
+
+// HandleSQS handles interactions delivered via SQS, e.g. by a separate function which acknowledges the interaction
+// before enqueuing it for slower processing. Signature verification is skipped as the queue is trusted, and deferred
+// responses are not sent as the interaction is expected to have been acknowledged already.
+// Records which fail to be processed are reported as batch item failures, so the function should be configured with
+// ReportBatchItemFailures.
+func (e *Endpoint) HandleSQS(ctx context.Context, event *events.SQSEvent) (res events.SQSEventResponse, err error) {
+	ctx, s := e.startSpan(ctx, "handle sqs")
+	defer func() { s.End(err) }()
+
+	ctx = e.withRequestID(ctx, "")
+
+	for _, record := range event.Records {
+		if err := e.handleAsync(ctx, []byte(record.Body)); err != nil {
+			e.logger(ctx).Error("Failed to handle SQS record", "message_id", record.MessageId, "error", err)
+			res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		}
+	}
+
+	return res, nil
+}
+
+// handleAsync handles an interaction received from a trusted source after it has been acknowledged
+func (e *Endpoint) handleAsync(ctx context.Context, body []byte) error {
+	i, err := e.decodeInteraction(body)
+	if err != nil {
+		return err
+	}
+
+	return e.handleAsyncInteraction(withEnvelope(ctx, readEnvelope(body)), i)
+}
+
+// decodeInteraction decodes an interaction received from a trusted source
+func (e *Endpoint) decodeInteraction(body []byte) (*discordgo.InteractionCreate, error) {
+	i, err := e.decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal interaction create: %w", err)
+	}
+	if i == nil || i.Interaction == nil {
+		return nil, errors.New("empty interaction")
+	}
+
+	return i, nil
+}
+
+func (e *Endpoint) handleAsyncInteraction(ctx context.Context, i *discordgo.InteractionCreate) (err error) {
+	ctx, seg := e.startSpan(ctx, "handle async interaction")
+	defer func() { seg.End(err) }()
+
+	annotate(seg, i)
+	e.logInteraction(ctx, i)
+
+	res, err := e.dispatch(ctx, e.interactionSession(i), i)
+	if isSessionError(err) && sessionprovider.IsPermanent(err) {
+		// retrying the interaction will not succeed, so it is discarded rather than reported as a failure
+		e.sessionFailed(ctx, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if res != nil {
+		e.logger(ctx).Warn("Discarding synchronous response to asynchronous interaction", "interaction_id", i.ID, "response_type", res.Type)
+	}
+
+	return nil
+}
+
+// HandleSNS handles interactions published to an SNS topic, e.g. to fan out interactions which have already been
+// acknowledged. As with HandleSQS, signature verification is skipped as the topic is trusted. Malformed messages are
+// logged and skipped, while errors handling interactions are returned so that the event is retried.
+func (e *Endpoint) HandleSNS(ctx context.Context, event *events.SNSEvent) (err error) {
+	ctx, s := e.startSpan(ctx, "handle sns")
+	defer func() { s.End(err) }()
+
+	ctx = e.withRequestID(ctx, "")
+
+	var errs []error
+	for _, record := range event.Records {
+		log := e.logger(ctx).With("message_id", record.SNS.MessageID)
+
+		body := []byte(record.SNS.Message)
+		i, err := e.decodeInteraction(body)
+		if err != nil {
+			log.Error("Skipping malformed SNS message", "error", err)
+			continue
+		}
+
+		if err := e.handleAsyncInteraction(withEnvelope(ctx, readEnvelope(body)), i); err != nil {
+			log.Error("Failed to handle SNS message", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// HandleKinesis handles interactions replayed from a Kinesis stream. As with HandleSQS, signature verification is
+// skipped as the stream is trusted. Malformed records are logged and skipped, as retrying them would block the shard,
+// while records which fail to be handled are reported as batch item failures, so the function should be configured
+// with ReportBatchItemFailures.
+func (e *Endpoint) HandleKinesis(ctx context.Context, event *events.KinesisEvent) (res events.KinesisEventResponse, err error) {
+	ctx, s := e.startSpan(ctx, "handle kinesis")
+	defer func() { s.End(err) }()
+
+	ctx = e.withRequestID(ctx, "")
+
+	for _, record := range event.Records {
+		log := e.logger(ctx).With("sequence_number", record.Kinesis.SequenceNumber)
+
+		body := record.Kinesis.Data
+		i, err := e.decodeInteraction(body)
+		if err != nil {
+			log.Error("Skipping malformed Kinesis record", "error", err)
+			continue
+		}
+
+		if err := e.handleAsyncInteraction(withEnvelope(ctx, readEnvelope(body)), i); err != nil {
+			log.Error("Failed to handle Kinesis record", "error", err)
+			res.BatchItemFailures = append(res.BatchItemFailures, events.KinesisBatchItemFailure{ItemIdentifier: record.Kinesis.SequenceNumber})
+		}
+	}
+
+	return res, nil
+}
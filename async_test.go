@@ -0,0 +1,185 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/sessionprovider"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandEndpoint returns an endpoint with the chat command foo registered, and a pointer to the number of calls
+func commandEndpoint(t *testing.T, options ...Option) (*Endpoint, *int) {
+	e := New(nil, append([]Option{WithLogger(slogt.New(t))}, options...)...)
+
+	calls := 0
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls++
+		return nil
+	})
+
+	return e, &calls
+}
+
+func TestHandleSQS(t *testing.T) {
+	e, calls := commandEndpoint(t)
+
+	body := string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}))
+
+	res, err := e.HandleSQS(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: body},
+		{MessageId: "2", Body: "{malformed"},
+		{MessageId: "3", Body: body},
+	}})
+
+	require.NoError(t, err)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "2"}}, res.BatchItemFailures)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestHandleSNS(t *testing.T) {
+	e, calls := commandEndpoint(t)
+
+	body := string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}))
+
+	err := e.HandleSNS(context.Background(), &events.SNSEvent{Records: []events.SNSEventRecord{
+		{SNS: events.SNSEntity{MessageID: "1", Message: body}},
+		{SNS: events.SNSEntity{MessageID: "2", Message: "{malformed"}},
+		{SNS: events.SNSEntity{MessageID: "3", Message: "null"}},
+		{SNS: events.SNSEntity{MessageID: "4", Message: body}},
+	}})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestHandleSNS_Error(t *testing.T) {
+	e, _ := commandEndpoint(t)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, errors.New("oops")
+	})
+
+	err := e.HandleSNS(context.Background(), &events.SNSEvent{Records: []events.SNSEventRecord{
+		{SNS: events.SNSEntity{MessageID: "1", Message: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}))}},
+	}})
+
+	assert.Error(t, err)
+}
+
+func TestHandleKinesis(t *testing.T) {
+	e, calls := commandEndpoint(t)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		if i, _ := sessionprovider.InteractionFromContext(ctx); i.ID == "failing" {
+			return nil, errors.New("oops")
+		}
+
+		return &discordgo.Session{}, nil
+	})
+
+	failing := fooCommand()
+	failing.ID = "failing"
+
+	res, err := e.HandleKinesis(context.Background(), &events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{SequenceNumber: "1", Data: mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "2", Data: []byte("{malformed")}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "3", Data: mustMarshal(t, &discordgo.InteractionCreate{Interaction: failing})}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "4", Data: mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})}},
+	}})
+
+	// then the malformed record should be skipped, and the record which failed reported
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, []events.KinesisBatchItemFailure{{ItemIdentifier: "3"}}, res.BatchItemFailures)
+}
+
+type fakeSQS struct {
+	queueURL string
+	bodies   []string
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, queueURL, body string) error {
+	f.queueURL = queueURL
+	f.bodies = append(f.bodies, body)
+
+	return nil
+}
+
+func TestWithDeferToSQS(t *testing.T) {
+	// the interaction response endpoint expects the deferred response
+	var callbacks []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbacks = append(callbacks, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+
+	q := &fakeSQS{}
+	e, calls := commandEndpoint(t, WithDiscordEndpoint(server.URL), WithDeferToSQS("queue_url", q))
+
+	res := send(t, e, &discordgo.Interaction{
+		ID:    "interaction_id",
+		Type:  discordgo.InteractionApplicationCommand,
+		Token: "interaction_token",
+		Data:  discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then the interaction should be deferred and enqueued without being handled
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, []string{"/api/v9/interactions/interaction_id/interaction_token/callback"}, callbacks)
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, "queue_url", q.queueURL)
+	require.Len(t, q.bodies, 1)
+	assert.Contains(t, q.bodies[0], "interaction_token")
+
+	// then the enqueued interaction can be handled by the worker
+	sqsRes, err := e.HandleSQS(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: q.bodies[0]}}})
+	require.NoError(t, err)
+	assert.Empty(t, sqsRes.BatchItemFailures)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWithDeferToSQS_Entitlements(t *testing.T) {
+	server, _ := recordingServer(t)
+	q := &fakeSQS{}
+	var entitled []bool
+	e, calls := commandEndpoint(t,
+		WithDiscordEndpoint(server.URL),
+		WithDeferToSQS("queue_url", q),
+		WithMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				entitled = append(entitled, HasEntitlement(ctx, "premium"))
+				return next(ctx, s, i)
+			}
+		}),
+	)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}), &body))
+	body["entitlements"] = []Entitlement{{SKUID: "premium"}}
+
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, body)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.Len(t, q.bodies, 1)
+
+	// when the enqueued interaction is handled by the worker
+	sqsRes, err := e.HandleSQS(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: q.bodies[0]}}})
+	require.NoError(t, err)
+	assert.Empty(t, sqsRes.BatchItemFailures)
+
+	// then the entitlements should be available to the handler
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, []bool{true}, entitled)
+}
@@ -0,0 +1,32 @@
+// Package bottest provides helpers for testing bots built with bot-lambda, e.g. signing requests so that they pass the
+// endpoint's signature verification.
+package bottest
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	// HeaderSignature is the header containing the hex-encoded ed25519 signature of the request.
+	HeaderSignature = "X-Signature-Ed25519"
+	// HeaderTimestamp is the header containing the timestamp the request was signed at.
+	HeaderTimestamp = "X-Signature-Timestamp"
+)
+
+// SignRequest signs the body with the private key as Discord would, returning the signature and timestamp headers to
+// send with the request. If the timestamp is empty the current time is used.
+func SignRequest(priv ed25519.PrivateKey, timestamp string, body []byte) (headers map[string]string) {
+	if timestamp == "" {
+		timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	sig := ed25519.Sign(priv, append([]byte(timestamp), body...))
+
+	return map[string]string{
+		HeaderSignature: hex.EncodeToString(sig),
+		HeaderTimestamp: timestamp,
+	}
+}
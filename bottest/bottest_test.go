@@ -0,0 +1,33 @@
+package bottest
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignRequest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	body := []byte(`{"type":1}`)
+	headers := SignRequest(priv, "1700000000", body)
+
+	assert.Equal(t, "1700000000", headers[HeaderTimestamp])
+
+	sig, err := hex.DecodeString(headers[HeaderSignature])
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(pub, append([]byte("1700000000"), body...), sig))
+}
+
+func TestSignRequest_DefaultTimestamp(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	headers := SignRequest(priv, "", nil)
+
+	assert.NotEmpty(t, headers[HeaderTimestamp])
+}
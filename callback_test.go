@@ -0,0 +1,60 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// rateLimitedServer rate limits the first n requests, then accepts them
+func rateLimitedServer(t *testing.T, n int, retryAfter string) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls <= n {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": ` + retryAfter + `, "global": false}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestSendDeferredResponse_RateLimited(t *testing.T) {
+	server, calls := rateLimitedServer(t, 1, "0.01")
+	e, handled := commandEndpoint(t, WithDiscordEndpoint(server.URL), WithDeferredResponseEnabled(true))
+
+	res := send(t, e, fooCommand())
+
+	// then the deferred response should be retried after the rate limit
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, 1, *handled)
+}
+
+func TestSendDeferredResponse_RateLimitedBeyondDeadline(t *testing.T) {
+	server, calls := rateLimitedServer(t, 1, "10")
+	e, _ := commandEndpoint(t, WithDiscordEndpoint(server.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	i := &discordgo.InteractionCreate{Interaction: fooCommand()}
+	err := e.sendDeferredResponse(withResponseGuard(ctx), i, e.interactionSession(i), e.deferredResponse)
+
+	// then the deferred response should not be retried
+	var rateLimited *discordgo.RateLimitError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.Equal(t, 1, *calls)
+}
@@ -0,0 +1,146 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// CloudFrontEvent is a Lambda@Edge event. It is defined here as aws-lambda-go does not provide CloudFront events.
+// See https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/lambda-event-structure.html
+type CloudFrontEvent struct {
+	Records []CloudFrontRecord `json:"Records"`
+}
+
+// CloudFrontRecord is a record of a CloudFrontEvent.
+type CloudFrontRecord struct {
+	CF struct {
+		Request CloudFrontRequest `json:"request"`
+	} `json:"cf"`
+}
+
+// CloudFrontRequest is the request received by CloudFront.
+type CloudFrontRequest struct {
+	ClientIP    string                        `json:"clientIp"`
+	Method      string                        `json:"method"`
+	URI         string                        `json:"uri"`
+	QueryString string                        `json:"querystring"`
+	Headers     map[string][]CloudFrontHeader `json:"headers"`
+	Body        *CloudFrontBody               `json:"body,omitempty"`
+}
+
+// CloudFrontHeader is a header value. CloudFront headers are keyed by their lowercase name.
+type CloudFrontHeader struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// CloudFrontBody is the request body, included if the function association has "Include body" enabled.
+type CloudFrontBody struct {
+	InputTruncated bool   `json:"inputTruncated"`
+	Action         string `json:"action"`
+	Encoding       string `json:"encoding"`
+	Data           string `json:"data"`
+}
+
+// CloudFrontResponse is a response generated by a Lambda@Edge function.
+type CloudFrontResponse struct {
+	Status            string                        `json:"status"`
+	StatusDescription string                        `json:"statusDescription,omitempty"`
+	Headers           map[string][]CloudFrontHeader `json:"headers,omitempty"`
+	Body              string                        `json:"body,omitempty"`
+	BodyEncoding      string                        `json:"bodyEncoding,omitempty"`
+}
+
+// HandleCloudFront is the lambda handler for Lambda@Edge origin-request events, which allows interactions to be
+// verified and handled at the edge, responding directly rather than forwarding the request to the origin.
+//
+// Lambda@Edge has the following constraints:
+//   - The function must be associated with the "Include body" option, or the body will be empty.
+//   - Request bodies over 1MB are truncated by CloudFront, so truncated requests are rejected with a 413.
+//   - Environment variables are not supported, so the public key must be compiled in or fetched at runtime, e.g. with
+//     NewFromHex or PublicKeyFromParamStore.
+//
+// CloudFront header names are normalised to lowercase, and only the first value of each header is used.
+func (e *Endpoint) HandleCloudFront(ctx context.Context, event *CloudFrontEvent) (res *CloudFrontResponse, err error) {
+	if len(event.Records) == 0 {
+		return nil, errors.New("cloudfront event has no records")
+	}
+
+	request := event.Records[0].CF.Request
+	headers := make(map[string]string, len(request.Headers))
+	for k, v := range request.Headers {
+		if len(v) > 0 {
+			headers[k] = v[0].Value
+		}
+	}
+
+	ctx, s := e.startSpan(e.tracer.Extract(ctx, headers), "handle cloudfront")
+	defer func() { s.End(err) }()
+
+	ctx = e.withRequestID(ctx, "")
+
+	if request.Method != http.MethodPost {
+		return e.cloudFrontResponse(e.unexpectedMethod(ctx, request.Method)), nil
+	}
+
+	e.logger(ctx).Debug("Received cloudfront request")
+
+	if request.Body != nil && request.Body.InputTruncated {
+		e.logger(ctx).Error("Cloudfront request body was truncated")
+		return e.cloudFrontResponse(http.StatusRequestEntityTooLarge, ""), nil
+	}
+
+	body, err := cloudFrontBody(request.Body)
+	if err != nil {
+		e.logger(ctx).Error("Invalid cloudfront request body", "error", err)
+		return e.cloudFrontResponse(http.StatusBadRequest, ""), nil
+	}
+
+	ctx = withRequestInfo(ctx, requestInfo{sourceIP: request.ClientIP, userAgent: headers["user-agent"]})
+
+	resBody, code, err := e.handle(ctx, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.cloudFrontResponse(code, resBody), nil
+}
+
+// cloudFrontBody decodes the request body, which is base64 encoded by default
+func cloudFrontBody(body *CloudFrontBody) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	if body.Encoding == "base64" {
+		bs, err := base64.StdEncoding.DecodeString(body.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode body: %w", err)
+		}
+
+		return bs, nil
+	}
+
+	return []byte(body.Data), nil
+}
+
+func (e *Endpoint) cloudFrontResponse(code int, body string) *CloudFrontResponse {
+	res := &CloudFrontResponse{
+		Status:            strconv.Itoa(code),
+		StatusDescription: http.StatusText(code),
+	}
+
+	if body != "" {
+		res.Body = body
+		res.BodyEncoding = "text"
+		res.Headers = map[string][]CloudFrontHeader{
+			"content-type": {{Key: "Content-Type", Value: e.contentType}},
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,79 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cloudFrontEvent(method string, body *CloudFrontBody, headers map[string][]CloudFrontHeader) *CloudFrontEvent {
+	var r CloudFrontRecord
+	r.CF.Request = CloudFrontRequest{Method: method, URI: "/", Headers: headers, Body: body}
+
+	return &CloudFrontEvent{Records: []CloudFrontRecord{r}}
+}
+
+func TestHandleCloudFront(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	e := New(publicKey, WithLogger(slogt.New(t)))
+
+	bs := mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := ed25519.Sign(privateKey, append([]byte(ts), bs...))
+
+	// when a signed ping is received at the edge
+	res, err := e.HandleCloudFront(context.Background(), cloudFrontEvent(
+		http.MethodPost,
+		&CloudFrontBody{Action: "read-only", Encoding: "base64", Data: base64.StdEncoding.EncodeToString(bs)},
+		map[string][]CloudFrontHeader{
+			"x-signature-ed25519":   {{Key: "X-Signature-Ed25519", Value: hex.EncodeToString(sig)}},
+			"x-signature-timestamp": {{Key: "X-Signature-Timestamp", Value: ts}},
+		},
+	))
+
+	// then a pong should be generated
+	require.NoError(t, err)
+	assert.Equal(t, "200", res.Status)
+	assert.Equal(t, "application/json", res.Headers["content-type"][0].Value)
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, discordgo.InteractionResponsePong, v.Type)
+}
+
+func TestHandleCloudFront_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  *CloudFrontEvent
+		status string
+	}{
+		{"invalid method", cloudFrontEvent(http.MethodGet, nil, nil), "405"},
+		{"truncated body", cloudFrontEvent(http.MethodPost, &CloudFrontBody{InputTruncated: true, Encoding: "text", Data: "{"}, nil), "413"},
+		{"invalid encoding", cloudFrontEvent(http.MethodPost, &CloudFrontBody{Encoding: "base64", Data: "!"}, nil), "400"},
+		{"unsigned", cloudFrontEvent(http.MethodPost, &CloudFrontBody{Encoding: "text", Data: `{"type":1}`}, nil), "401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, _, err := ed25519.GenerateKey(nil)
+			require.NoError(t, err)
+			e := New(publicKey, WithLogger(slogt.New(t)))
+
+			res, err := e.HandleCloudFront(context.Background(), tt.event)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.Status)
+		})
+	}
+}
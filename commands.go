@@ -0,0 +1,159 @@
+package bot_lambda
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+)
+
+type commandKey struct {
+	name        string
+	commandType discordgo.ApplicationCommandType
+}
+
+// CommandOptions declaratively configures how the endpoint handles a command.
+type CommandOptions struct {
+	// Deferred sends a deferred response before the command is handled, regardless of WithDeferredResponseEnabled.
+	Deferred bool
+	// Ephemeral makes the deferred response ephemeral. Only applies if Deferred is true.
+	Ephemeral bool
+	// Timeout sets a deadline on the handler's context.
+	Timeout time.Duration
+}
+
+func (o CommandOptions) deferredResponse() *discordgo.InteractionResponse {
+	res := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{},
+	}
+
+	if o.Ephemeral {
+		res.Data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
+	return res
+}
+
+// commandHandler wraps the handler with the behaviour configured for the endpoint and command
+func (e *Endpoint) commandHandler(h router.ApplicationCommandHandler, options CommandOptions) router.ApplicationCommandHandler {
+	h = e.withRetry(h)
+
+	if options.Timeout > 0 {
+		next := h
+		h = func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+			ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+			defer cancel()
+
+			return next(ctx, s, i, data)
+		}
+	}
+
+	return h
+}
+
+// chatCommandName matches valid chat command names.
+// See https://discord.com/developers/docs/interactions/application-commands#application-command-object-application-command-naming
+var chatCommandName = regexp.MustCompile(`^[-_'\p{L}\p{N}\p{Devanagari}\p{Thai}]{1,32}$`)
+
+// ValidateCommandName checks the name against Discord's application command naming rules. Commands registered with
+// invalid names will never be dispatched by Discord.
+func ValidateCommandName(name string, commandType discordgo.ApplicationCommandType) error {
+	switch commandType {
+	case discordgo.ChatApplicationCommand:
+		if !chatCommandName.MatchString(name) {
+			return fmt.Errorf("invalid chat command name %q: must be 1-32 characters without spaces or symbols", name)
+		}
+		if strings.ToLower(name) != name {
+			return fmt.Errorf("invalid chat command name %q: must be lowercase", name)
+		}
+	default:
+		if n := utf8.RuneCountInString(name); n < 1 || n > 32 {
+			return fmt.Errorf("invalid command name %q: must be 1-32 characters", name)
+		}
+	}
+
+	return nil
+}
+
+// Command describes an application command to be registered with WithCommandList.
+type Command struct {
+	Name    string
+	Type    discordgo.ApplicationCommandType
+	Handler router.ApplicationCommandHandler
+	Options CommandOptions
+}
+
+// WithCommands registers a discordgo.ChatApplicationCommand for each handler in the map, keyed by command name.
+func (e *Endpoint) WithCommands(handlers map[string]router.ApplicationCommandHandler) *Endpoint {
+	return e.withCommandMap(discordgo.ChatApplicationCommand, handlers)
+}
+
+// WithUserCommands registers a discordgo.UserApplicationCommand for each handler in the map, keyed by command name.
+func (e *Endpoint) WithUserCommands(handlers map[string]router.ApplicationCommandHandler) *Endpoint {
+	return e.withCommandMap(discordgo.UserApplicationCommand, handlers)
+}
+
+// WithMessageCommands registers a discordgo.MessageApplicationCommand for each handler in the map, keyed by command
+// name.
+func (e *Endpoint) WithMessageCommands(handlers map[string]router.ApplicationCommandHandler) *Endpoint {
+	return e.withCommandMap(discordgo.MessageApplicationCommand, handlers)
+}
+
+// WithCommandList registers each of the commands, which may be of any type and configured with options.
+func (e *Endpoint) WithCommandList(commands ...Command) *Endpoint {
+	for _, c := range commands {
+		e.WithApplicationCommandOptions(c.Name, c.Type, c.Handler, c.Options)
+	}
+
+	return e
+}
+
+// MustWithApplicationCommand registers a new application command as with WithApplicationCommand, but panics if a
+// command with the same name and type is already registered, so that copy-paste mistakes fail fast at startup.
+func (e *Endpoint) MustWithApplicationCommand(name string, commandType discordgo.ApplicationCommandType, handler router.ApplicationCommandHandler) *Endpoint {
+	if _, ok := e.handlers[commandKey{name: name, commandType: commandType}]; ok {
+		panic(fmt.Sprintf("bot_lambda: duplicate registration of command %q of type %d", name, commandType))
+	}
+
+	return e.WithApplicationCommand(name, commandType, handler)
+}
+
+// withCommandMap registers the handlers in name order, so that registration is deterministic
+func (e *Endpoint) withCommandMap(commandType discordgo.ApplicationCommandType, handlers map[string]router.ApplicationCommandHandler) *Endpoint {
+	for _, name := range slices.Sorted(maps.Keys(handlers)) {
+		e.WithApplicationCommand(name, commandType, handlers[name])
+	}
+
+	return e
+}
+
+// CommandInfo describes a command registered with the endpoint.
+type CommandInfo struct {
+	Name string
+	Type discordgo.ApplicationCommandType
+}
+
+// RegisteredCommands returns the commands registered with the endpoint, ordered by type and then name, e.g. to compare
+// against the commands registered with Discord during a deployment. Commands registered directly with a router passed
+// to WithRouter are not included.
+func (e *Endpoint) RegisteredCommands() []CommandInfo {
+	commands := make([]CommandInfo, 0, len(e.commands))
+	for k := range e.commands {
+		commands = append(commands, CommandInfo{Name: k.name, Type: k.commandType})
+	}
+
+	slices.SortFunc(commands, func(a, b CommandInfo) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Name, b.Name))
+	})
+
+	return commands
+}
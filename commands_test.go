@@ -0,0 +1,239 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCommandName(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandType discordgo.ApplicationCommandType
+		wantErr     bool
+	}{
+		{name: "foo", commandType: discordgo.ChatApplicationCommand},
+		{name: "foo-bar_baz", commandType: discordgo.ChatApplicationCommand},
+		{name: "ping2", commandType: discordgo.ChatApplicationCommand},
+		{name: "don't", commandType: discordgo.ChatApplicationCommand},
+		{name: "こんにちは", commandType: discordgo.ChatApplicationCommand},
+		{name: "Foo Bar", commandType: discordgo.MessageApplicationCommand},
+		{name: "Foo Bar", commandType: discordgo.UserApplicationCommand},
+		{name: "Foo Bar", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "Foo", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "foo bar", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "foo!", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "", commandType: discordgo.MessageApplicationCommand, wantErr: true},
+		{name: "abcdefghijklmnopqrstuvwxyzabcdefg", commandType: discordgo.ChatApplicationCommand, wantErr: true},
+		{name: "abcdefghijklmnopqrstuvwxyzabcdefg", commandType: discordgo.UserApplicationCommand, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCommandName(tt.name, tt.commandType)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// callbackServer records the interaction responses sent to the Discord API
+func callbackServer(t *testing.T) (*httptest.Server, *[]*discordgo.InteractionResponse) {
+	var received []*discordgo.InteractionResponse
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var res *discordgo.InteractionResponse
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		received = append(received, res)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestCommandOptions_DeferredEphemeral(t *testing.T) {
+	server, received := callbackServer(t)
+	e := New(nil, WithLogger(slogt.New(t)), WithDiscordEndpoint(server.URL))
+
+	calls := 0
+	e.WithApplicationCommandOptions("foo", discordgo.ChatApplicationCommand, func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls++
+		return nil
+	}, CommandOptions{Deferred: true, Ephemeral: true})
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, calls)
+	require.Len(t, *received, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, (*received)[0].Type)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, (*received)[0].Data.Flags)
+}
+
+func TestCommandOptions_Deferred(t *testing.T) {
+	server, received := callbackServer(t)
+	e := New(nil, WithLogger(slogt.New(t)), WithDiscordEndpoint(server.URL))
+
+	e.WithApplicationCommandOptions("foo", discordgo.ChatApplicationCommand, func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return nil
+	}, CommandOptions{Deferred: true})
+
+	send(t, e, fooCommand())
+
+	require.Len(t, *received, 1)
+	assert.Zero(t, (*received)[0].Data.Flags)
+}
+
+func TestCommandOptions_Timeout(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	var deadline time.Time
+	e.WithApplicationCommandOptions("foo", discordgo.ChatApplicationCommand, func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}, CommandOptions{Timeout: time.Minute})
+
+	send(t, e, fooCommand())
+
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestWithApplicationCommandDeferred(t *testing.T) {
+	server, received := callbackServer(t)
+	e := New(nil, WithLogger(slogt.New(t)), WithDiscordEndpoint(server.URL))
+
+	calls := map[string]int{}
+	handler := func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls[data.Name]++
+		return nil
+	}
+	e.WithApplicationCommandDeferred("slow", discordgo.ChatApplicationCommand, handler)
+	e.WithChatApplicationCommand("fast", handler)
+
+	command := func(name string) *discordgo.Interaction {
+		return &discordgo.Interaction{
+			Type: discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{Name: name, CommandType: discordgo.ChatApplicationCommand},
+		}
+	}
+
+	// when the fast command is received then no deferred response is sent
+	send(t, e, command("fast"))
+	assert.Empty(t, *received)
+
+	// when the slow command is received then a deferred response is sent
+	send(t, e, command("slow"))
+	require.Len(t, *received, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, (*received)[0].Type)
+
+	assert.Equal(t, map[string]int{"fast": 1, "slow": 1}, calls)
+}
+
+func TestWithCommands(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	calls := map[string]int{}
+	handler := func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls[data.Name]++
+		return nil
+	}
+	e.WithCommands(map[string]router.ApplicationCommandHandler{"foo": handler, "bar": handler}).
+		WithUserCommands(map[string]router.ApplicationCommandHandler{"Profile": handler}).
+		WithCommandList(Command{Name: "Pin", Type: discordgo.MessageApplicationCommand, Handler: handler})
+
+	for _, c := range []struct {
+		name        string
+		commandType discordgo.ApplicationCommandType
+	}{
+		{"foo", discordgo.ChatApplicationCommand},
+		{"bar", discordgo.ChatApplicationCommand},
+		{"Profile", discordgo.UserApplicationCommand},
+		{"Pin", discordgo.MessageApplicationCommand},
+	} {
+		send(t, e, &discordgo.Interaction{
+			Type: discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{Name: c.name, CommandType: c.commandType},
+		})
+	}
+
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 1, "Profile": 1, "Pin": 1}, calls)
+}
+
+func TestRegisteredCommands(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+	handler := func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return nil
+	}
+
+	e.WithChatApplicationCommand("foo", handler).
+		WithChatApplicationCommand("bar", handler).
+		WithMessageApplicationCommand("Pin", handler).
+		WithUserApplicationCommand("Profile", handler).
+		WithSubcommand("config", "get", nil)
+
+	assert.Equal(t, []CommandInfo{
+		{Name: "bar", Type: discordgo.ChatApplicationCommand},
+		{Name: "config", Type: discordgo.ChatApplicationCommand},
+		{Name: "foo", Type: discordgo.ChatApplicationCommand},
+		{Name: "Profile", Type: discordgo.UserApplicationCommand},
+		{Name: "Pin", Type: discordgo.MessageApplicationCommand},
+	}, e.RegisteredCommands())
+}
+
+func TestWithApplicationCommand_Duplicate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var handled string
+	handler := func(name string) router.ApplicationCommandHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+			handled = name
+			return nil
+		}
+	}
+
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil)))).
+		WithChatApplicationCommand("foo", handler("first")).
+		WithChatApplicationCommand("foo", handler("second")).
+		WithUserApplicationCommand("foo", handler("user"))
+
+	// then the duplicate registration should be logged, but not the same name with a different type
+	records := logRecords(t, buf)
+	var warnings int
+	for _, r := range records {
+		if r["msg"] == "Overwriting duplicate command registration" {
+			warnings++
+		}
+	}
+	assert.Equal(t, 1, warnings)
+
+	// and the last registration should handle the command
+	send(t, e, fooCommand())
+	assert.Equal(t, "second", handled)
+}
+
+func TestMustWithApplicationCommand(t *testing.T) {
+	handler := func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		return nil
+	}
+
+	e := New(nil, WithLogger(slogt.New(t))).
+		MustWithApplicationCommand("foo", discordgo.ChatApplicationCommand, handler).
+		MustWithApplicationCommand("foo", discordgo.UserApplicationCommand, handler)
+
+	assert.PanicsWithValue(t, `bot_lambda: duplicate registration of command "foo" of type 1`, func() {
+		e.MustWithApplicationCommand("foo", discordgo.ChatApplicationCommand, handler)
+	})
+}
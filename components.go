@@ -0,0 +1,72 @@
+package bot_lambda
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultCustomIDSeparator separates the prefix and parameters of a custom ID, e.g. "vote:poll123:yes"
+const defaultCustomIDSeparator = ":"
+
+// ComponentHandler handles a message component (or modal submit) interaction, receiving the parameters encoded in the
+// custom ID after the prefix it was registered with.
+type ComponentHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, params []string) (*discordgo.InteractionResponse, error)
+
+// WithCustomIDSeparator overrides the separator used to split custom IDs into a prefix and parameters. Defaults to ":".
+func WithCustomIDSeparator(sep string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.customIDSeparator = sep
+	}
+}
+
+// WithComponentHandler registers a handler for message component and modal submit interactions with custom IDs
+// beginning with the prefix. The remainder of the custom ID is split into parameters, e.g. a button with custom ID
+// "vote:poll123:yes" is passed to the handler registered with prefix "vote" with parameters ["poll123", "yes"]. If
+// multiple prefixes match then the longest is used.
+func (e *Endpoint) WithComponentHandler(prefix string, handler ComponentHandler) *Endpoint {
+	e.components[prefix] = handler
+
+	return e
+}
+
+// componentHandler returns the handler for the interaction's custom ID, and the parameters to pass to it
+func (e *Endpoint) componentHandler(i *discordgo.InteractionCreate) (ComponentHandler, []string, bool) {
+	var customID string
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		customID = i.MessageComponentData().CustomID
+	case discordgo.InteractionModalSubmit:
+		customID = i.ModalSubmitData().CustomID
+	default:
+		return nil, nil, false
+	}
+
+	var match string
+	var handler ComponentHandler
+	for prefix, h := range e.components {
+		if handler != nil && len(prefix) <= len(match) {
+			continue
+		}
+		if customID == prefix || strings.HasPrefix(customID, prefix+e.customIDSeparator) {
+			match, handler = prefix, h
+		}
+	}
+
+	if handler == nil {
+		return nil, nil, false
+	}
+
+	return handler, customIDParams(strings.TrimPrefix(customID, match), e.customIDSeparator), true
+}
+
+// customIDParams splits the remainder of a custom ID after its prefix into parameters
+func customIDParams(rest, sep string) []string {
+	rest = strings.TrimPrefix(rest, sep)
+	if rest == "" {
+		return nil
+	}
+
+	return strings.Split(rest, sep)
+}
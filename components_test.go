@@ -0,0 +1,171 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func componentInteraction(customID string) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type: discordgo.InteractionMessageComponent,
+		Data: discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}
+}
+
+func TestWithComponentHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		sep      string
+		customID string
+		handler  string
+		params   []string
+	}{
+		{name: "no params", customID: "vote", handler: "vote", params: nil},
+		{name: "one param", customID: "vote:poll123", handler: "vote", params: []string{"poll123"}},
+		{name: "multiple params", customID: "vote:poll123:yes", handler: "vote", params: []string{"poll123", "yes"}},
+		{name: "longest prefix", customID: "vote:admin:close:poll123", handler: "vote:admin", params: []string{"close", "poll123"}},
+		{name: "custom separator", sep: "|", customID: "vote|poll123|yes", handler: "vote", params: []string{"poll123", "yes"}},
+		{name: "partial prefix", customID: "voter:poll123", handler: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []Option{WithLogger(slogt.New(t))}
+			if tt.sep != "" {
+				options = append(options, WithCustomIDSeparator(tt.sep))
+			}
+
+			var handler string
+			var params []string
+			handle := func(name string) ComponentHandler {
+				return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p []string) (*discordgo.InteractionResponse, error) {
+					handler, params = name, p
+					return AckResponse(), nil
+				}
+			}
+
+			e := New(nil, options...).
+				WithComponentHandler("vote", handle("vote")).
+				WithComponentHandler("vote:admin", handle("vote:admin"))
+
+			res := send(t, e, componentInteraction(tt.customID))
+
+			assert.Equal(t, tt.handler, handler)
+			assert.Equal(t, tt.params, params)
+			if tt.handler != "" {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestWithComponentHandler_ModalSubmit(t *testing.T) {
+	var params []string
+	e := New(nil, WithLogger(slogt.New(t))).
+		WithComponentHandler("feedback", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p []string) (*discordgo.InteractionResponse, error) {
+			params = p
+			return nil, nil
+		})
+
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionModalSubmit,
+		Data: discordgo.ModalSubmitInteractionData{CustomID: "feedback:1234"},
+	})
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, []string{"1234"}, params)
+}
+
+func TestWithComponentHandler_UpdateMessage(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t))).
+		WithComponentHandler("vote", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p []string) (*discordgo.InteractionResponse, error) {
+			return UpdateMessageResponse(&discordgo.InteractionResponseData{Content: "Voted for " + p[0]}), nil
+		})
+
+	// when a button is clicked
+	res := send(t, e, componentInteraction("vote:yes"))
+
+	// then the button's message should be updated
+	var body discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &body))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, discordgo.InteractionResponseUpdateMessage, body.Type)
+	assert.Equal(t, "Voted for yes", body.Data.Content)
+}
+
+func TestWithDeferredResponseEnabled_Component(t *testing.T) {
+	tests := []struct {
+		name   string
+		res    *discordgo.InteractionResponse
+		method string
+		path   string
+	}{
+		{
+			name:   "update message edits the original message",
+			res:    UpdateMessageResponse(&discordgo.InteractionResponseData{Content: "Voted"}),
+			method: http.MethodPatch,
+			path:   "/api/v9/webhooks/app/interaction_token/messages/@original",
+		},
+		{
+			name:   "new message is sent as a follow-up",
+			res:    &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: &discordgo.InteractionResponseData{Content: "Voted"}},
+			method: http.MethodPost,
+			path:   "/api/v9/webhooks/app/interaction_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := recordingServer(t)
+			e := New(nil, WithLogger(slogt.New(t)), WithDiscordEndpoint(server.URL), WithDeferredResponseEnabled(true)).
+				WithComponentHandler("vote", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p []string) (*discordgo.InteractionResponse, error) {
+					return tt.res, nil
+				})
+
+			// when a button is clicked
+			i := componentInteraction("vote:yes")
+			i.ID, i.AppID, i.Token = "interaction_id", "app", "interaction_token"
+			res := send(t, e, i)
+			assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+			// then the interaction should be deferred with a message update
+			got := requests()
+			require.Len(t, got, 2)
+			assert.Equal(t, "/api/v9/interactions/interaction_id/interaction_token/callback", got[0].path)
+			assert.JSONEq(t, `{"type": 6}`, string(got[0].body))
+
+			// and the handler's message should be sent
+			assert.Equal(t, tt.method, got[1].method)
+			assert.Equal(t, tt.path, got[1].path)
+			assert.Contains(t, string(got[1].body), "Voted")
+		})
+	}
+}
+
+func TestWithDeferredResponseEnabled_ComponentAck(t *testing.T) {
+	server, requests := recordingServer(t)
+	buf := &bytes.Buffer{}
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithDiscordEndpoint(server.URL), WithDeferredResponseEnabled(true)).
+		WithComponentHandler("vote", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p []string) (*discordgo.InteractionResponse, error) {
+			return AckResponse(), nil
+		})
+
+	// when a component handler acknowledges the interaction without a message
+	i := componentInteraction("vote:yes")
+	i.ID, i.AppID, i.Token = "interaction_id", "app", "interaction_token"
+	res := send(t, e, i)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	// then only the deferred response should be sent, without discarding the handler's response
+	require.Len(t, requests(), 1)
+	assert.Nil(t, findRecord(logRecords(t, buf), "Discarding response to deferred interaction"))
+}
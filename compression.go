@@ -0,0 +1,119 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// compressionThreshold is the minimum size of a response body in bytes before it is compressed, below which the
+// overhead of gzip outweighs the saving
+const compressionThreshold = 1024
+
+// WithResponseCompression enables gzip compression of synchronous responses larger than 1KiB when the request
+// advertises support with an Accept-Encoding header. Compressed bodies are base64 encoded as required by Lambda.
+func WithResponseCompression(enabled bool) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.compressResponses = enabled
+	}
+}
+
+// compress gzips the body if compression is enabled and accepted by the request, returning the body, the response
+// headers and whether the body is base64 encoded
+func (e *Endpoint) compress(headers map[string]string, body string) (string, map[string]string, bool, error) {
+	if !e.compressResponses || len(body) < compressionThreshold {
+		return body, nil, false, nil
+	}
+
+	// the response depends on the Accept-Encoding header, so caches must not serve it to clients which sent another
+	resHeaders := addVary(nil, "Accept-Encoding")
+	if !acceptsGzip(headerValue(headers, "Accept-Encoding")) {
+		return body, resHeaders, false, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return "", nil, false, fmt.Errorf("compress response: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, false, fmt.Errorf("compress response: %w", err)
+	}
+
+	resHeaders["Content-Encoding"] = "gzip"
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), resHeaders, true, nil
+}
+
+// acceptsGzip returns true if the Accept-Encoding header value includes gzip, excluding where it has a zero q-value
+func acceptsGzip(accept string) bool {
+	for _, enc := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(enc, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// errBodyTooLarge is returned when a decompressed request body exceeds the maximum body size
+var errBodyTooLarge = errors.New("request body too large")
+
+// requestBody returns the request body, decoding it if Lambda has base64 encoded it, e.g. because it is compressed
+func requestBody(body string, base64Encoded bool) ([]byte, error) {
+	if !base64Encoded {
+		return []byte(body), nil
+	}
+
+	bs, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode request body: %w", err)
+	}
+
+	return bs, nil
+}
+
+// decompress decompresses the request body if a proxy has gzipped it and set the Content-Encoding header. Discord signs
+// the uncompressed body, so it is the decompressed body which is verified; proxies which re-sign the body they send
+// must sign the uncompressed body, using a key configured in place of the application's public key. The decompressed
+// body is limited to the maximum body size (see WithMaxBodySize) to protect against decompression bombs.
+func (e *Endpoint) decompress(headers map[string]string, body []byte) ([]byte, error) {
+	if !strings.EqualFold(strings.TrimSpace(headerValue(headers, "Content-Encoding")), "gzip") {
+		return body, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("decompress request body: %w", err)
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if e.maxBodySize > 0 {
+		src = io.LimitReader(r, int64(e.maxBodySize)+1)
+	}
+
+	bs, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("decompress request body: %w", err)
+	}
+
+	if e.maxBodySize > 0 && len(bs) > e.maxBodySize {
+		return nil, errBodyTooLarge
+	}
+
+	return bs, nil
+}
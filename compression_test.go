@@ -0,0 +1,143 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/bottest"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func largeResponse() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{Description: strings.Repeat("a", 2*compressionThreshold)}},
+		},
+	}
+}
+
+func TestWithResponseCompression(t *testing.T) {
+	e, _ := commandEndpoint(t, WithResponseCompression(true), WithMiddleware(responding(largeResponse())))
+
+	// when the request accepts gzip
+	res := sendWithHeaders(t, e, fooCommand(), map[string]string{"accept-encoding": "deflate, gzip;q=1.0"})
+
+	// then the response should be gzip encoded
+	assert.Equal(t, "gzip", res.Headers["Content-Encoding"])
+	assert.Equal(t, "Accept-Encoding", res.Headers["Vary"])
+	require.True(t, res.IsBase64Encoded)
+
+	bs, err := base64.StdEncoding.DecodeString(res.Body)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(bs))
+	require.NoError(t, err)
+	bs, err = io.ReadAll(r)
+	require.NoError(t, err)
+
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal(bs, &v))
+	assert.Equal(t, largeResponse().Data.Embeds[0].Description, v.Data.Embeds[0].Description)
+}
+
+func TestWithResponseCompression_CORS(t *testing.T) {
+	e, _ := commandEndpoint(t, WithResponseCompression(true), WithCORS("https://tool.example"), WithMiddleware(responding(largeResponse())))
+
+	// when a cross-origin request accepts gzip
+	res := sendWithHeaders(t, e, fooCommand(), map[string]string{"Accept-Encoding": "gzip", "Origin": "https://tool.example"})
+
+	// then the response should vary by both the encoding and the origin
+	assert.Equal(t, "gzip", res.Headers["Content-Encoding"])
+	assert.Equal(t, "Accept-Encoding, Origin", res.Headers["Vary"])
+}
+
+func TestWithResponseCompression_NotCompressed(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		res     *discordgo.InteractionResponse
+		headers map[string]string
+	}{
+		{"disabled", false, largeResponse(), map[string]string{"Accept-Encoding": "gzip"}},
+		{"not accepted", true, largeResponse(), nil},
+		{"refused", true, largeResponse(), map[string]string{"Accept-Encoding": "gzip;q=0, br"}},
+		{"below threshold", true, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "small"},
+		}, map[string]string{"Accept-Encoding": "gzip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := commandEndpoint(t, WithResponseCompression(tt.enabled), WithMiddleware(responding(tt.res)))
+
+			res := sendWithHeaders(t, e, fooCommand(), tt.headers)
+
+			assert.False(t, res.IsBase64Encoded)
+			assert.Empty(t, res.Headers["Content-Encoding"])
+			assert.True(t, json.Valid([]byte(res.Body)))
+		})
+	}
+}
+
+func gzipped(t *testing.T, body []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestHandle_GzipRequestBody(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	// Discord signs the uncompressed body
+	body := mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})
+	headers := bottest.SignRequest(privateKey, "", body)
+	headers["Content-Encoding"] = "gzip"
+
+	tests := []struct {
+		name    string
+		options []Option
+		body    []byte
+		code    int
+	}{
+		{name: "gzipped", body: gzipped(t, body), code: http.StatusOK},
+		{name: "corrupt", body: []byte("not gzip"), code: http.StatusBadRequest},
+		{name: "too large", options: []Option{WithMaxBodySize(len(body) - 1)}, body: gzipped(t, body), code: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(publicKey, append(tt.options, WithLogger(slogt.New(t)))...)
+
+			// when a proxy sends the compressed body, which Lambda base64 encodes
+			res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+				},
+				Headers:         headers,
+				Body:            base64.StdEncoding.EncodeToString(tt.body),
+				IsBase64Encoded: true,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package bot_lambda
+
+// defaultContentType is the content type of synchronous responses
+const defaultContentType = "application/json"
+
+// WithContentType overrides the Content-Type header of synchronous responses, e.g. to include a charset. Responses
+// without a body, such as 202s, do not have a Content-Type header.
+func WithContentType(contentType string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.contentType = contentType
+	}
+}
+
+// encodeResponse compresses the response body if accepted by the request, returning the body, the response headers
+// and whether the body is base64 encoded
+func (e *Endpoint) encodeResponse(requestHeaders map[string]string, body string) (string, map[string]string, bool, error) {
+	if body == "" {
+		return body, nil, false, nil
+	}
+
+	body, headers, encoded, err := e.compress(requestHeaders, body)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Content-Type"] = e.contentType
+
+	return body, headers, encoded, nil
+}
@@ -0,0 +1,33 @@
+package bot_lambda
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentType(t *testing.T) {
+	message := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello"},
+	}
+
+	// a synchronous response has a JSON content type
+	e, _ := commandEndpoint(t, WithMiddleware(responding(message)))
+	res := send(t, e, fooCommand())
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Headers["Content-Type"])
+
+	// the content type can be overridden
+	e, _ = commandEndpoint(t, WithMiddleware(responding(message)), WithContentType("application/json; charset=utf-8"))
+	res = send(t, e, fooCommand())
+	assert.Equal(t, "application/json; charset=utf-8", res.Headers["Content-Type"])
+
+	// an empty response has no content type
+	e, _ = commandEndpoint(t)
+	res = send(t, e, fooCommand())
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.NotContains(t, res.Headers, "Content-Type")
+}
@@ -0,0 +1,43 @@
+package bot_lambda
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type sessionKey struct{}
+
+type tokenKey struct{}
+
+type requestInfoKey struct{}
+
+// requestInfo describes the source of the request being handled, as reported by the Lambda event
+type requestInfo struct {
+	sourceIP  string
+	userAgent string
+}
+
+func withRequestInfo(ctx context.Context, info requestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func requestInfoFromContext(ctx context.Context) requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+
+	return info
+}
+
+// SessionFromContext returns the session resolved for the interaction being handled.
+func SessionFromContext(ctx context.Context) (*discordgo.Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*discordgo.Session)
+
+	return s, ok
+}
+
+// TokenFromContext returns the token of the interaction being handled, which can be used to send follow-up messages.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+
+	return token, ok
+}
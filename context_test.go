@@ -0,0 +1,61 @@
+package bot_lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionFromContext(t *testing.T) {
+	session := &discordgo.Session{Token: "Bot foo"}
+	e := New(nil, WithLogger(slogt.New(t))).WithSession(session)
+
+	var got *discordgo.Session
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		var ok bool
+		got, ok = SessionFromContext(ctx)
+		assert.True(t, ok)
+
+		return nil
+	})
+
+	send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	require.NotNil(t, got)
+	assert.Same(t, session, got)
+}
+
+func TestSessionFromContext_Missing(t *testing.T) {
+	s, ok := SessionFromContext(context.Background())
+
+	assert.False(t, ok)
+	assert.Nil(t, s)
+}
+
+func TestTokenFromContext(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	var got string
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		var ok bool
+		got, ok = TokenFromContext(ctx)
+		assert.True(t, ok)
+
+		return nil
+	})
+
+	send(t, e, &discordgo.Interaction{
+		Type:  discordgo.InteractionApplicationCommand,
+		Token: "interaction_token",
+		Data:  discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	assert.Equal(t, "interaction_token", got)
+}
@@ -0,0 +1,64 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/elliotwms/bot-lambda/tracing"
+)
+
+type correlationIDKey struct{}
+
+// WithCorrelationHeader reads a correlation ID (such as X-Request-ID) from the named request header, generating one if
+// it is absent. The ID is added to the endpoint's logs and traces, and is available to handlers via
+// CorrelationIDFromContext.
+func WithCorrelationHeader(name string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.correlationHeader = name
+	}
+}
+
+// CorrelationIDFromContext returns the correlation ID of the request being handled. See WithCorrelationHeader.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+
+	return id, ok
+}
+
+// correlate adds the request's correlation ID to the context, logger and span if a correlation header is configured
+func (e *Endpoint) correlate(ctx context.Context, headers map[string]string, span tracing.Span) context.Context {
+	if e.correlationHeader == "" {
+		return ctx
+	}
+
+	id := headerValue(headers, e.correlationHeader)
+	if id == "" {
+		id = newCorrelationID()
+	}
+
+	span.SetAttribute("correlation_id", id)
+	ctx = context.WithValue(ctx, correlationIDKey{}, id)
+
+	return e.withLogAttrs(ctx, "correlation_id", id)
+}
+
+// headerValue returns the value of the header, matching the name case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	name = http.CanonicalHeaderKey(name)
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == name {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
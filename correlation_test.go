@@ -0,0 +1,57 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func correlationEndpoint(t *testing.T) (*Endpoint, *bytes.Buffer, *string) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	e := New(nil, WithLogger(l), WithCorrelationHeader("X-Request-ID"))
+
+	var id string
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		var ok bool
+		id, ok = CorrelationIDFromContext(ctx)
+		assert.True(t, ok)
+
+		return nil
+	})
+
+	return e, buf, &id
+}
+
+func TestWithCorrelationHeader(t *testing.T) {
+	e, buf, id := correlationEndpoint(t)
+
+	sendWithHeaders(t, e, fooCommand(), map[string]string{"x-request-id": "request_id"})
+
+	assert.Equal(t, "request_id", *id)
+	r := findRecord(logRecords(t, buf), "Handling interaction")
+	require.NotNil(t, r)
+	assert.Equal(t, "request_id", r["correlation_id"])
+}
+
+func TestWithCorrelationHeader_Generated(t *testing.T) {
+	e, buf, id := correlationEndpoint(t)
+
+	send(t, e, fooCommand())
+
+	assert.Len(t, *id, 32)
+	r := findRecord(logRecords(t, buf), "Handling interaction")
+	require.NotNil(t, r)
+	assert.Equal(t, *id, r["correlation_id"])
+}
+
+func TestCorrelationIDFromContext_Missing(t *testing.T) {
+	_, ok := CorrelationIDFromContext(context.Background())
+
+	assert.False(t, ok)
+}
@@ -0,0 +1,59 @@
+package bot_lambda
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers browsers may send to the endpoint
+var corsAllowedHeaders = strings.Join([]string{"Content-Type", headerSignature, headerTimestamp}, ", ")
+
+// WithCORS adds CORS headers to responses to requests from the origins, and responds to preflight OPTIONS requests,
+// e.g. for browser-based testing tools. Use "*" to allow any origin. Discord does not require CORS.
+func WithCORS(origins ...string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.corsOrigins = origins
+	}
+}
+
+// preflight returns the headers to respond to a CORS preflight request with, or false if the request is not a
+// preflight request handled by the endpoint
+func (e *Endpoint) preflight(method string, headers map[string]string) (map[string]string, bool) {
+	if method != http.MethodOptions || len(e.corsOrigins) == 0 {
+		return nil, false
+	}
+
+	res := e.withCORSHeaders(headers, nil)
+	if res != nil {
+		res["Access-Control-Allow-Methods"] = "POST, OPTIONS"
+		res["Access-Control-Allow-Headers"] = corsAllowedHeaders
+		res["Access-Control-Max-Age"] = "86400"
+	}
+
+	return res, true
+}
+
+// withCORSHeaders adds CORS headers to the response headers if the request's origin is allowed
+func (e *Endpoint) withCORSHeaders(reqHeaders map[string]string, headers map[string]string) map[string]string {
+	origin := headerValue(reqHeaders, "Origin")
+	if origin == "" || len(e.corsOrigins) == 0 {
+		return headers
+	}
+
+	allowed := origin
+	switch {
+	case slices.Contains(e.corsOrigins, "*"):
+		allowed = "*"
+	case !slices.Contains(e.corsOrigins, origin):
+		return headers
+	}
+
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Access-Control-Allow-Origin"] = allowed
+	headers = addVary(headers, "Origin")
+
+	return headers
+}
@@ -0,0 +1,57 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCORS_Preflight(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithCORS("https://tool.example"))
+
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodOptions},
+		},
+		Headers: map[string]string{"origin": "https://tool.example"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "https://tool.example", res.Headers["Access-Control-Allow-Origin"])
+	assert.Equal(t, "POST, OPTIONS", res.Headers["Access-Control-Allow-Methods"])
+	assert.Contains(t, res.Headers["Access-Control-Allow-Headers"], "X-Signature-Ed25519")
+}
+
+func TestWithCORS_Post(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    string
+	}{
+		{name: "allowed origin", origins: []string{"https://tool.example"}, origin: "https://tool.example", want: "https://tool.example"},
+		{name: "any origin", origins: []string{"*"}, origin: "https://tool.example", want: "*"},
+		{name: "disallowed origin", origins: []string{"https://tool.example"}, origin: "https://other.example", want: ""},
+		{name: "disabled", origin: "https://tool.example", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(nil, WithLogger(slogt.New(t)), WithCORS(tt.origins...))
+
+			res := sendWithHeaders(t, e, &discordgo.Interaction{Type: discordgo.InteractionPing}, map[string]string{
+				"Origin": tt.origin,
+			})
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, tt.want, res.Headers["Access-Control-Allow-Origin"])
+		})
+	}
+}
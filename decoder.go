@@ -0,0 +1,51 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// InteractionDecoder decodes the request body into an interaction.
+type InteractionDecoder func(body []byte) (*discordgo.InteractionCreate, error)
+
+// WithInteractionDecoder overrides the decoder used for interactions, e.g. to reject unknown fields or to use a faster
+// JSON library. The default decoder uses json.Unmarshal. Note that discordgo.Interaction implements json.Unmarshaler,
+// so json.Decoder's DisallowUnknownFields does not apply to the interaction's fields.
+func WithInteractionDecoder(d InteractionDecoder) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.decode = d
+	}
+}
+
+// envelope holds the fields of the request body which discordgo.Interaction does not decode, so that they are read in
+// a single pass over the body rather than one per field
+type envelope struct {
+	installation
+	Type         *int            `json:"type"`
+	Event        json.RawMessage `json:"event"`
+	Entitlements []Entitlement   `json:"entitlements"`
+}
+
+// readEnvelope reads the envelope from the body, returning an empty envelope if the body cannot be decoded
+func readEnvelope(body []byte) envelope {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return envelope{}
+	}
+
+	return env
+}
+
+// withEnvelope adds the entitlements and installation context read from the body to the context
+func withEnvelope(ctx context.Context, env envelope) context.Context {
+	return withInstallation(withEntitlements(ctx, env.Entitlements), env.installation)
+}
+
+func unmarshalInteraction(body []byte) (*discordgo.InteractionCreate, error) {
+	var i *discordgo.InteractionCreate
+	err := json.Unmarshal(body, &i)
+
+	return i, err
+}
@@ -0,0 +1,93 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// strictDecoder rejects interactions with top level fields other than those expected. discordgo.Interaction implements
+// json.Unmarshaler, so json.Decoder's DisallowUnknownFields has no effect.
+func strictDecoder(body []byte) (*discordgo.InteractionCreate, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	for k := range fields {
+		if !slices.Contains([]string{"id", "application_id", "type", "data", "token", "version"}, k) {
+			return nil, fmt.Errorf("unknown field %q", k)
+		}
+	}
+
+	var i *discordgo.InteractionCreate
+	err := json.Unmarshal(body, &i)
+
+	return i, err
+}
+
+func TestWithInteractionDecoder(t *testing.T) {
+	e, calls := commandEndpoint(t, WithInteractionDecoder(strictDecoder))
+
+	request := func(body string) error {
+		_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+			},
+			Body: body,
+		})
+
+		return err
+	}
+
+	// when the interaction only has expected fields then it is handled
+	assert.NoError(t, request(`{"type":2,"token":"foo","data":{"name":"foo","type":1}}`))
+	assert.Equal(t, 1, *calls)
+
+	// when the interaction has an unexpected field then it is rejected
+	assert.ErrorContains(t, request(`{"type":2,"token":"foo","data":{"name":"foo","type":1},"unexpected":true}`), `unknown field "unexpected"`)
+	assert.Equal(t, 1, *calls)
+}
+
+// largeComponentPayload builds a component interaction on a message with many large embeds
+func largeComponentPayload(b *testing.B) []byte {
+	embeds := make([]*discordgo.MessageEmbed, 10)
+	for n := range embeds {
+		embeds[n] = &discordgo.MessageEmbed{Title: fmt.Sprintf("embed %d", n), Description: strings.Repeat("lorem ipsum ", 300)}
+	}
+
+	i := componentInteraction("vote:1")
+	i.Message = &discordgo.Message{ID: "message_id", Content: strings.Repeat("dolor sit amet ", 100), Embeds: embeds}
+
+	body, err := json.Marshal(&discordgo.InteractionCreate{Interaction: i})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return body
+}
+
+func BenchmarkHandle_LargePayload(b *testing.B) {
+	e := New(nil).WithComponentHandler("vote", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, params []string) (*discordgo.InteractionResponse, error) {
+		return nil, nil
+	})
+	body := largeComponentPayload(b)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+
+	for range b.N {
+		if _, code, err := e.handle(context.Background(), nil, body); err != nil || code != http.StatusAccepted {
+			b.Fatalf("unexpected result: %d %v", code, err)
+		}
+	}
+}
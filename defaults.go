@@ -0,0 +1,147 @@
+package bot_lambda
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// EmbedMergeMode describes how the default embeds are merged with the embeds of a response.
+type EmbedMergeMode int
+
+const (
+	// EmbedsReplace uses the response's embeds if any are set, otherwise the default embeds.
+	EmbedsReplace EmbedMergeMode = iota
+	// EmbedsAppend appends the default embeds after the response's embeds.
+	EmbedsAppend
+	// EmbedsMerge uses the first default embed as a template for each of the response's embeds, filling any unset
+	// fields (e.g. footer or color).
+	EmbedsMerge
+)
+
+// MessageFlagsNoDefaults can be set in the flags of a handler's response to send it without the default flags and TTS
+// (see WithResponseDefaults), e.g. to make a response public when the defaults are ephemeral. It is removed before the
+// response is sent.
+const MessageFlagsNoDefaults discordgo.MessageFlags = 1 << 30
+
+// WithResponseDefaults merges the default data into message responses. Fields set by the handler take precedence over
+// the defaults, so the default flags are only used if the handler sets none, and the default TTS only if the handler
+// does not enable it. As unset flags and TTS cannot be told apart from cleared ones, a handler can clear them by setting
+// MessageFlagsNoDefaults. See WithEmbedMergeMode to configure how embeds are merged.
+func WithResponseDefaults(defaults *discordgo.InteractionResponseData) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.responseDefaults = defaults
+	}
+}
+
+// WithEmbedMergeMode configures how embeds are merged when WithResponseDefaults is used. Defaults to EmbedsReplace.
+func WithEmbedMergeMode(mode EmbedMergeMode) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.embedMergeMode = mode
+	}
+}
+
+// applyDefaults returns a copy of the response with the default data merged in
+func (e *Endpoint) applyDefaults(res *discordgo.InteractionResponse) *discordgo.InteractionResponse {
+	if res == nil {
+		return res
+	}
+
+	if res.Type != discordgo.InteractionResponseChannelMessageWithSource && res.Type != discordgo.InteractionResponseUpdateMessage {
+		return res
+	}
+
+	if e.responseDefaults == nil {
+		return withoutNoDefaultsFlag(res)
+	}
+
+	merged := *res
+	merged.Data = mergeResponseData(e.responseDefaults, res.Data, e.embedMergeMode)
+
+	return &merged
+}
+
+func mergeResponseData(defaults, data *discordgo.InteractionResponseData, mode EmbedMergeMode) *discordgo.InteractionResponseData {
+	if data == nil {
+		data = &discordgo.InteractionResponseData{}
+	}
+
+	merged := *data
+	if data.Flags&MessageFlagsNoDefaults != 0 {
+		merged.Flags = data.Flags &^ MessageFlagsNoDefaults
+	} else {
+		merged.TTS = firstNonZero(data.TTS, defaults.TTS)
+		merged.Flags = firstNonZero(data.Flags, defaults.Flags)
+	}
+	merged.Content = firstNonZero(data.Content, defaults.Content)
+	merged.Title = firstNonZero(data.Title, defaults.Title)
+	merged.CustomID = firstNonZero(data.CustomID, defaults.CustomID)
+
+	if merged.AllowedMentions == nil {
+		merged.AllowedMentions = defaults.AllowedMentions
+	}
+	if merged.Components == nil {
+		merged.Components = defaults.Components
+	}
+	if merged.Attachments == nil {
+		merged.Attachments = defaults.Attachments
+	}
+
+	switch mode {
+	case EmbedsAppend:
+		merged.Embeds = append(append([]*discordgo.MessageEmbed{}, data.Embeds...), defaults.Embeds...)
+	case EmbedsMerge:
+		if len(defaults.Embeds) > 0 {
+			merged.Embeds = make([]*discordgo.MessageEmbed, len(data.Embeds))
+			for i, embed := range data.Embeds {
+				merged.Embeds[i] = mergeEmbed(defaults.Embeds[0], embed)
+			}
+		}
+	default:
+		if merged.Embeds == nil {
+			merged.Embeds = defaults.Embeds
+		}
+	}
+
+	return &merged
+}
+
+// withoutNoDefaultsFlag returns a copy of the response without MessageFlagsNoDefaults, if it is set
+func withoutNoDefaultsFlag(res *discordgo.InteractionResponse) *discordgo.InteractionResponse {
+	if res.Data == nil || res.Data.Flags&MessageFlagsNoDefaults == 0 {
+		return res
+	}
+
+	data := *res.Data
+	data.Flags &^= MessageFlagsNoDefaults
+	stripped := *res
+	stripped.Data = &data
+
+	return &stripped
+}
+
+func mergeEmbed(defaults, embed *discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	merged := *embed
+	merged.URL = firstNonZero(embed.URL, defaults.URL)
+	merged.Title = firstNonZero(embed.Title, defaults.Title)
+	merged.Description = firstNonZero(embed.Description, defaults.Description)
+	merged.Timestamp = firstNonZero(embed.Timestamp, defaults.Timestamp)
+	merged.Color = firstNonZero(embed.Color, defaults.Color)
+	merged.Footer = firstNonZero(embed.Footer, defaults.Footer)
+	merged.Image = firstNonZero(embed.Image, defaults.Image)
+	merged.Thumbnail = firstNonZero(embed.Thumbnail, defaults.Thumbnail)
+	merged.Author = firstNonZero(embed.Author, defaults.Author)
+
+	if merged.Fields == nil {
+		merged.Fields = defaults.Fields
+	}
+
+	return &merged
+}
+
+func firstNonZero[T comparable](v, fallback T) T {
+	var zero T
+	if v == zero {
+		return fallback
+	}
+
+	return v
+}
@@ -0,0 +1,167 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responding returns middleware which responds to every interaction with the response
+func responding(res *discordgo.InteractionResponse) Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			return res, nil
+		}
+	}
+}
+
+var defaultFooter = &discordgo.MessageEmbedFooter{Text: "footer"}
+
+func defaultsEndpoint(t *testing.T, res *discordgo.InteractionResponse, options ...Option) *Endpoint {
+	options = append([]Option{
+		WithLogger(slogt.New(t)),
+		WithMiddleware(responding(res)),
+		WithResponseDefaults(&discordgo.InteractionResponseData{
+			Flags:  discordgo.MessageFlagsEphemeral,
+			Embeds: []*discordgo.MessageEmbed{{Color: 0xff0000, Footer: defaultFooter}},
+		}),
+	}, options...)
+
+	return New(nil, options...)
+}
+
+func sendForResponse(t *testing.T, e *Endpoint) *discordgo.InteractionResponse {
+	res := send(t, e, fooCommand())
+
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+
+	return v
+}
+
+func TestResponseDefaults(t *testing.T) {
+	e := defaultsEndpoint(t, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello"},
+	})
+
+	res := sendForResponse(t, e)
+
+	assert.Equal(t, "hello", res.Data.Content)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, res.Data.Flags)
+	require.Len(t, res.Data.Embeds, 1)
+	assert.Equal(t, 0xff0000, res.Data.Embeds[0].Color)
+}
+
+func TestResponseDefaults_Flags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags discordgo.MessageFlags
+		want  discordgo.MessageFlags
+	}{
+		{name: "unset flags use the default", flags: 0, want: discordgo.MessageFlagsEphemeral},
+		{name: "handler flags replace the default", flags: discordgo.MessageFlagsSuppressEmbeds, want: discordgo.MessageFlagsSuppressEmbeds},
+		{name: "no defaults clears the default", flags: MessageFlagsNoDefaults, want: 0},
+		{name: "no defaults keeps handler flags", flags: MessageFlagsNoDefaults | discordgo.MessageFlagsSuppressEmbeds, want: discordgo.MessageFlagsSuppressEmbeds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := defaultsEndpoint(t, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Content: "hello", Flags: tt.flags},
+			})
+
+			res := sendForResponse(t, e)
+
+			assert.Equal(t, tt.want, res.Data.Flags)
+		})
+	}
+}
+
+func TestResponseDefaults_TTS(t *testing.T) {
+	res := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello"},
+	}
+	e := New(nil, WithLogger(slogt.New(t)), WithMiddleware(responding(res)), WithResponseDefaults(&discordgo.InteractionResponseData{TTS: true}))
+
+	// the default TTS is used when the handler does not enable it
+	assert.True(t, sendForResponse(t, e).Data.TTS)
+
+	// and cleared when the handler opts out of the defaults
+	res.Data.Flags = MessageFlagsNoDefaults
+	v := sendForResponse(t, e)
+	assert.False(t, v.Data.TTS)
+	assert.Zero(t, v.Data.Flags)
+}
+
+func TestResponseDefaults_NoDefaultsWithoutDefaults(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithMiddleware(responding(&discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello", Flags: MessageFlagsNoDefaults},
+	})))
+
+	// the flag is removed even if no defaults are configured
+	assert.Zero(t, sendForResponse(t, e).Data.Flags)
+}
+
+func TestResponseDefaults_Replace(t *testing.T) {
+	e := defaultsEndpoint(t, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{{Title: "a"}}},
+	})
+
+	res := sendForResponse(t, e)
+
+	require.Len(t, res.Data.Embeds, 1)
+	assert.Equal(t, "a", res.Data.Embeds[0].Title)
+	assert.Zero(t, res.Data.Embeds[0].Color)
+}
+
+func TestResponseDefaults_Append(t *testing.T) {
+	e := defaultsEndpoint(t, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{{Title: "a"}}},
+	}, WithEmbedMergeMode(EmbedsAppend))
+
+	res := sendForResponse(t, e)
+
+	require.Len(t, res.Data.Embeds, 2)
+	assert.Equal(t, "a", res.Data.Embeds[0].Title)
+	assert.Equal(t, 0xff0000, res.Data.Embeds[1].Color)
+}
+
+func TestResponseDefaults_Merge(t *testing.T) {
+	e := defaultsEndpoint(t, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{
+			{Title: "a"},
+			{Title: "b", Color: 0x00ff00},
+		}},
+	}, WithEmbedMergeMode(EmbedsMerge))
+
+	res := sendForResponse(t, e)
+
+	require.Len(t, res.Data.Embeds, 2)
+	assert.Equal(t, "a", res.Data.Embeds[0].Title)
+	assert.Equal(t, 0xff0000, res.Data.Embeds[0].Color)
+	assert.Equal(t, defaultFooter, res.Data.Embeds[0].Footer)
+	assert.Equal(t, "b", res.Data.Embeds[1].Title)
+	assert.Equal(t, 0x00ff00, res.Data.Embeds[1].Color, "handler fields should take precedence")
+	assert.Equal(t, defaultFooter, res.Data.Embeds[1].Footer)
+}
+
+func TestResponseDefaults_NonMessageResponse(t *testing.T) {
+	e := defaultsEndpoint(t, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource})
+
+	res := sendForResponse(t, e)
+
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, res.Type)
+	assert.Nil(t, res.Data)
+}
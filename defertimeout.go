@@ -0,0 +1,165 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// WithDeferOnTimeout runs handlers synchronously, returning their response if they respond within d. If the handler
+// takes longer then a deferred response is sent and the endpoint waits for the handler to complete, sending the
+// handler's message if it returns one. This gives fast handlers the lowest latency while
+// keeping slow handlers within Discord's 3-second initial response deadline, so d should allow for the time taken to
+// receive the interaction, e.g. 2.5s.
+//
+// Application commands and modal submissions are deferred with the endpoint's deferred response (see
+// WithDeferredResponse), and message components are deferred without updating the message. Autocomplete interactions
+// cannot be deferred. Interactions which are already deferred (see WithDeferredResponseEnabled) are not affected.
+func WithDeferOnTimeout(d time.Duration) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.deferOnTimeout = d
+	}
+}
+
+type dispatchResult struct {
+	res *discordgo.InteractionResponse
+	err error
+}
+
+// dispatchOrDefer dispatches the interaction, sending a deferred response if the handler exceeds the defer timeout
+func (e *Endpoint) dispatchOrDefer(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+	deferred := e.timeoutDeferredResponse(i)
+	if deferred == nil {
+		return e.dispatch(ctx, s, i)
+	}
+
+	done := make(chan dispatchResult, 1)
+	e.inflight.Add(1)
+	go func() {
+		defer e.inflight.Done()
+		res, err := e.dispatch(ctx, s, i)
+		done <- dispatchResult{res: res, err: err}
+	}()
+
+	t := time.NewTimer(e.deferOnTimeout)
+	defer t.Stop()
+
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-t.C:
+	}
+
+	e.logger(ctx).Debug("Handler exceeded defer timeout, sending deferred response")
+	deferErr := e.sendDeferredResponse(ctx, i, s, deferred)
+	if errors.Is(deferErr, ErrAlreadyResponded) {
+		// the handler has responded itself, so there is nothing to defer
+		r := <-done
+		return r.res, r.err
+	}
+
+	// the handler must complete before the invocation ends, as the Lambda function may be frozen once it returns
+	r := <-done
+	if deferErr != nil {
+		return nil, errors.Join(fmt.Errorf("sending deferred response: %w", deferErr), r.err)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.res != nil {
+		if err := e.completeDeferredResponse(ctx, s, i, deferred, r.res); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// timeoutDeferredResponse returns the response used to defer the interaction if the handler times out, or nil if the
+// interaction cannot be deferred
+func (e *Endpoint) timeoutDeferredResponse(i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionModalSubmit:
+		return e.deferredResponse
+	case discordgo.InteractionMessageComponent:
+		return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	default:
+		return nil
+	}
+}
+
+// completeDeferredResponse sends the message in the handler's response once the interaction has been deferred. A
+// message update (type 7) edits the original message. A new message (type 4) also edits the original response when
+// the interaction was deferred with a loading state (type 5). When it was deferred without one (type 6), the original
+// response is the component's message, so the new message is sent as a follow-up.
+func (e *Endpoint) completeDeferredResponse(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, deferred, res *discordgo.InteractionResponse) error {
+	if res.Type == discordgo.InteractionResponseDeferredMessageUpdate && deferred.Type == discordgo.InteractionResponseDeferredMessageUpdate {
+		// the handler acknowledged the interaction without a message, which the deferred response already has
+		return nil
+	}
+
+	switch res.Type {
+	case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseUpdateMessage:
+	default:
+		e.logger(ctx).Warn("Discarding response to deferred interaction", "interaction_id", i.ID, "response_type", res.Type)
+		return nil
+	}
+
+	if res.Data == nil {
+		return nil
+	}
+
+	if deferred.Type == discordgo.InteractionResponseDeferredMessageUpdate && res.Type == discordgo.InteractionResponseChannelMessageWithSource {
+		return e.sendFollowup(ctx, s, i, res.Data)
+	}
+
+	return e.editDeferredResponse(ctx, s, i, res.Data)
+}
+
+// editDeferredResponse edits the original response with the message
+func (e *Endpoint) editDeferredResponse(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) (err error) {
+	ctx, seg := e.startSpan(ctx, "edit deferred response")
+	defer func() { seg.End(err) }()
+
+	edit := &discordgo.WebhookEdit{
+		Content:         &data.Content,
+		AllowedMentions: data.AllowedMentions,
+	}
+	if len(data.Embeds) > 0 {
+		edit.Embeds = &data.Embeds
+	}
+	if len(data.Components) > 0 {
+		edit.Components = &data.Components
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, edit, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("edit deferred response: %w", err)
+	}
+
+	return nil
+}
+
+// sendFollowup sends the message as a follow-up to the interaction
+func (e *Endpoint) sendFollowup(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) (err error) {
+	ctx, seg := e.startSpan(ctx, "send followup message")
+	defer func() { seg.End(err) }()
+
+	_, err = s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+		Content:         data.Content,
+		TTS:             data.TTS,
+		Components:      data.Components,
+		Embeds:          data.Embeds,
+		AllowedMentions: data.AllowedMentions,
+		Flags:           data.Flags,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("send followup message: %w", err)
+	}
+
+	return nil
+}
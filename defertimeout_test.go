@@ -0,0 +1,123 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	method, path string
+	body         []byte
+}
+
+// recordingServer records the requests made to the Discord API
+func recordingServer(t *testing.T) (*httptest.Server, func() []recordedRequest) {
+	var mu sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, recordedRequest{method: r.Method, path: r.URL.Path, body: bs})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requests
+	}
+}
+
+// slowly responds with the message after the delay
+func slowly(delay time.Duration, content string) Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			time.Sleep(delay)
+
+			return &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Content: content},
+			}, nil
+		}
+	}
+}
+
+func TestWithDeferOnTimeout_Fast(t *testing.T) {
+	server, requests := recordingServer(t)
+	e, _ := commandEndpoint(t, WithDeferOnTimeout(time.Second), WithDiscordEndpoint(server.URL), WithMiddleware(slowly(0, "fast")))
+
+	res := send(t, e, fooCommand())
+
+	// then the response should be returned synchronously
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, "fast", v.Data.Content)
+	assert.Empty(t, requests())
+}
+
+func TestWithDeferOnTimeout_Slow(t *testing.T) {
+	server, requests := recordingServer(t)
+	e, _ := commandEndpoint(t, WithDeferOnTimeout(10*time.Millisecond), WithDiscordEndpoint(server.URL), WithMiddleware(slowly(100*time.Millisecond, "slow")))
+
+	i := fooCommand()
+	i.AppID = "app"
+	i.Token = "interaction_token"
+	res := send(t, e, i)
+
+	// then the interaction should be deferred
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	got := requests()
+	require.Len(t, got, 2)
+	assert.Equal(t, http.MethodPost, got[0].method)
+	var deferred *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal(got[0].body, &deferred))
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, deferred.Type)
+
+	// and the deferred response should be edited with the handler's response
+	assert.Equal(t, http.MethodPatch, got[1].method)
+	assert.Equal(t, "/api/v9/webhooks/app/interaction_token/messages/@original", got[1].path)
+	var edit *discordgo.WebhookEdit
+	require.NoError(t, json.Unmarshal(got[1].body, &edit))
+	assert.Equal(t, "slow", *edit.Content)
+}
+
+func TestWithDeferOnTimeout_SlowComponent(t *testing.T) {
+	server, requests := recordingServer(t)
+	e := New(nil, WithLogger(slogt.New(t)), WithDeferOnTimeout(10*time.Millisecond), WithDiscordEndpoint(server.URL), WithMiddleware(slowly(100*time.Millisecond, "slow")))
+
+	// when a slow component handler responds with a new message
+	i := componentInteraction("vote:yes")
+	i.ID, i.AppID, i.Token = "interaction_id", "app", "interaction_token"
+	res := send(t, e, i)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	// then the interaction should be deferred with a message update
+	got := requests()
+	require.Len(t, got, 2)
+	assert.JSONEq(t, `{"type": 6}`, string(got[0].body))
+
+	// and the message should be sent as a follow-up rather than replacing the component's message
+	assert.Equal(t, http.MethodPost, got[1].method)
+	assert.Equal(t, "/api/v9/webhooks/app/interaction_token", got[1].path)
+	var params *discordgo.WebhookParams
+	require.NoError(t, json.Unmarshal(got[1].body, &params))
+	assert.Equal(t, "slow", params.Content)
+}
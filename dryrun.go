@@ -0,0 +1,73 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// DryRunCall is a request to the Discord API which was recorded instead of being sent. See WithDryRun.
+type DryRunCall struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// WithDryRun records requests to the Discord API, such as deferred responses and follow-up messages, instead of sending
+// them, e.g. to exercise handlers in CI smoke tests without Discord. The session provider is not called, and handlers
+// receive the interaction-scoped session so that their requests are recorded too. Recorded calls are logged, and can be
+// retrieved with DryRunCalls.
+func WithDryRun(enabled bool) Option {
+	return func(endpoint *Endpoint) {
+		if enabled {
+			endpoint.dryRun = &dryRunTransport{}
+		} else {
+			endpoint.dryRun = nil
+		}
+	}
+}
+
+// DryRunCalls returns the requests recorded in dry-run mode, in the order they were made. See WithDryRun.
+func (e *Endpoint) DryRunCalls() []DryRunCall {
+	if e.dryRun == nil {
+		return nil
+	}
+
+	e.dryRun.mu.Lock()
+	defer e.dryRun.mu.Unlock()
+
+	return append([]DryRunCall(nil), e.dryRun.calls...)
+}
+
+// dryRunTransport records requests and responds with an empty JSON object
+type dryRunTransport struct {
+	log   *slog.Logger
+	mu    sync.Mutex
+	calls []DryRunCall
+}
+
+func (t *dryRunTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		_ = r.Body.Close()
+	}
+
+	t.log.Info("Dry run, not sending request to Discord", "method", r.Method, "path", r.URL.Path)
+
+	t.mu.Lock()
+	t.calls = append(t.calls, DryRunCall{Method: r.Method, Path: r.URL.Path, Body: body})
+	t.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(`{}`))),
+		Request:    r,
+	}, nil
+}
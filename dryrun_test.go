@@ -0,0 +1,57 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDryRun(t *testing.T) {
+	var provided bool
+	e := New(nil, WithLogger(slogt.New(t)), WithDryRun(true), WithDeferredResponseEnabled(true)).
+		WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+			provided = true
+			return &discordgo.Session{}, nil
+		}).
+		WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+			_, err := s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{Content: "hello"})
+			return err
+		})
+
+	i := fooCommand()
+	i.ID = "interaction_id"
+	i.AppID = "app_id"
+	i.Token = "interaction_token"
+	res := send(t, e, i)
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.False(t, provided)
+
+	// then the requests which would have been sent should be recorded
+	calls := e.DryRunCalls()
+	require.Len(t, calls, 2)
+
+	assert.Equal(t, http.MethodPost, calls[0].Method)
+	assert.Equal(t, "/api/v9/interactions/interaction_id/interaction_token/callback", calls[0].Path)
+	var deferred *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal(calls[0].Body, &deferred))
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, deferred.Type)
+
+	assert.Equal(t, http.MethodPost, calls[1].Method)
+	assert.Equal(t, "/api/v9/webhooks/app_id/interaction_token", calls[1].Path)
+	var followup *discordgo.WebhookParams
+	require.NoError(t, json.Unmarshal(calls[1].Body, &followup))
+	assert.Equal(t, "hello", followup.Content)
+}
+
+func TestWithDryRun_Disabled(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	assert.Nil(t, e.DryRunCalls())
+}
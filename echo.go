@@ -0,0 +1,41 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// NewEcho creates an Endpoint which acknowledges every interaction with an ephemeral message naming the command (or
+// custom ID) invoked, without calling any handlers. This is useful for verifying the path from Discord to the Lambda
+// function before writing any handlers. Requests are still verified using the public key.
+func NewEcho(publicKey ed25519.PublicKey, options ...Option) *Endpoint {
+	return New(publicKey, append(options, WithMiddleware(echo))...)
+}
+
+// echo short-circuits the middleware chain, responding with a description of the interaction
+func echo(InteractionHandler) InteractionHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+		var content string
+		switch i.Type {
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			return AutocompleteResponse(), nil
+		case discordgo.InteractionApplicationCommand:
+			content = fmt.Sprintf("Received command %q", i.ApplicationCommandData().Name)
+		case discordgo.InteractionMessageComponent:
+			content = fmt.Sprintf("Received component %q", i.MessageComponentData().CustomID)
+		case discordgo.InteractionModalSubmit:
+			content = fmt.Sprintf("Received modal %q", i.ModalSubmitData().CustomID)
+		}
+
+		return &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,36 @@
+package bot_lambda
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEcho(t *testing.T) {
+	e := NewEcho(nil, WithLogger(slogt.New(t)))
+
+	res := send(t, e, fooCommand())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, discordgo.InteractionResponseChannelMessageWithSource, v.Type)
+	assert.Contains(t, v.Data.Content, "foo")
+}
+
+func TestNewEcho_Verifies(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	e := NewEcho(publicKey, WithLogger(slogt.New(t)))
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
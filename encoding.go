@@ -0,0 +1,35 @@
+package bot_lambda
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Encoding is the encoding of the request signature.
+type Encoding int
+
+const (
+	// EncodingHex is the hex encoding used by Discord
+	EncodingHex Encoding = iota
+	// EncodingBase64URL is the URL-safe base64 encoding, with or without padding
+	EncodingBase64URL
+)
+
+// WithSignatureEncoding sets the expected encoding of the request signature, for webhook sources or proxies which
+// re-encode the signature. Discord signatures are hex encoded, which is the default.
+func WithSignatureEncoding(enc Encoding) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.signatureEncoding = enc
+	}
+}
+
+// decode decodes the signature
+func (enc Encoding) decode(signature string) ([]byte, error) {
+	switch enc {
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(signature, "="))
+	default:
+		return hex.DecodeString(signature)
+	}
+}
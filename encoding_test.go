@@ -0,0 +1,60 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSignatureEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		encode  func([]byte) string
+		status  int
+	}{
+		{"hex by default", nil, hex.EncodeToString, http.StatusOK},
+		{"hex", []Option{WithSignatureEncoding(EncodingHex)}, hex.EncodeToString, http.StatusOK},
+		{"base64url", []Option{WithSignatureEncoding(EncodingBase64URL)}, base64.RawURLEncoding.EncodeToString, http.StatusOK},
+		{"padded base64url", []Option{WithSignatureEncoding(EncodingBase64URL)}, base64.URLEncoding.EncodeToString, http.StatusOK},
+		{"base64url when hex expected", nil, base64.RawURLEncoding.EncodeToString, http.StatusUnauthorized},
+		{"hex when base64url expected", []Option{WithSignatureEncoding(EncodingBase64URL)}, hex.EncodeToString, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, privateKey, err := ed25519.GenerateKey(nil)
+			require.NoError(t, err)
+			e := New(publicKey, append([]Option{WithLogger(slogt.New(t))}, tt.options...)...)
+
+			body := mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			sig := ed25519.Sign(privateKey, append([]byte(ts), body...))
+
+			res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+				},
+				Headers: map[string]string{
+					headerSignature: tt.encode(sig),
+					headerTimestamp: ts,
+				},
+				Body: string(body),
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
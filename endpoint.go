@@ -1,19 +1,23 @@
 package bot_lambda
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/bwmarrin/discordgo"
 	"github.com/elliotwms/bot-lambda/sessionprovider"
+	"github.com/elliotwms/bot-lambda/tracing"
 	"github.com/elliotwms/bot/interactions/router"
 	"github.com/elliotwms/bot/log"
 )
@@ -23,33 +27,147 @@ const (
 	headerTimestamp = "X-Signature-Timestamp"
 )
 
+var errNilSession = errors.New("session provider returned nil session without error")
+
 type Endpoint struct {
 	s                       sessionprovider.Provider
 	publicKey               ed25519.PublicKey
 	router                  *router.Router
 	log                     *slog.Logger
 	deferredResponseEnabled bool
+	deferredResponse        *discordgo.InteractionResponse
+	signatureHeader         string
+	parseSignatureHeader    SignatureHeaderParser
+	preFilter               PreFilter
+	middleware              []scopedMiddleware
+	handlerRetry            *handlerRetry
+	responseDefaults        *discordgo.InteractionResponseData
+	embedMergeMode          EmbedMergeMode
+	httpClient              *http.Client
+	discordBaseURL          string
+	discordEndpoint         *url.URL
+	tracer                  tracing.Tracer
+	interactionLogLevel     *slog.Level
+	deferQueue              *deferQueue
+	commands                map[commandKey]CommandOptions
+	metrics                 Metrics
+	unhandled               UnhandledInteractionHandler
+	correlationHeader       string
+	compressResponses       bool
+	webhookEventHandler     WebhookEventHandler
+	handlerTimeout          time.Duration
+	subcommands             map[string]map[string]SubcommandHandler
+	decode                  InteractionDecoder
+	verificationFailure     VerificationFailureHandler
+	signatureEncoding       Encoding
+	contentType             string
+	requireVerification     bool
+	deferOnTimeout          time.Duration
+	sessionClient           *http.Client
+	segmentPrefix           string
+	allowedApplicationIDs   []string
+	components              map[string]ComponentHandler
+	customIDSeparator       string
+	inflight                sync.WaitGroup
+	healthChecks            map[string]healthCheckResponse
+	preSessionMiddleware    []scopedMiddleware
+	localizer               Localizer
+	defaultLocale           discordgo.Locale
+	corsOrigins             []string
+	maxTimestampAge         time.Duration
+	nonces                  NonceStore
+	handlers                map[commandKey]router.ApplicationCommandHandler
+	routerOnce              sync.Once
+	built                   bool
+	typeHandlers            map[discordgo.InteractionType]InteractionTypeHandler
+	maxBodySize             int
+	dryRun                  *dryRunTransport
+	ackStatusCode           int
+	userAgent               string
 }
 
 func New(publicKey ed25519.PublicKey, options ...Option) *Endpoint {
 	logger := slog.New(log.DiscardHandler)
 
 	e := &Endpoint{
-		publicKey: publicKey,
-		log:       logger,
-		router:    router.New(router.WithLogger(logger)),
+		publicKey:         publicKey,
+		log:               logger,
+		tracer:            tracing.XRay(),
+		commands:          map[commandKey]CommandOptions{},
+		handlers:          map[commandKey]router.ApplicationCommandHandler{},
+		subcommands:       map[string]map[string]SubcommandHandler{},
+		components:        map[string]ComponentHandler{},
+		customIDSeparator: defaultCustomIDSeparator,
+		metrics:           noopMetrics{},
+		decode:            unmarshalInteraction,
+		contentType:       defaultContentType,
+		defaultLocale:     defaultLocale,
+		maxBodySize:       defaultMaxBodySize,
+		ackStatusCode:     http.StatusAccepted,
+		deferredResponse:  defaultDeferredResponse(),
 	}
 
 	for _, o := range options {
 		o(e)
 	}
 
+	if e.deferredResponse == nil || e.deferredResponse.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		e.log.Error("Deferred response must be a deferred channel message, using the default deferred response")
+		e.deferredResponse = defaultDeferredResponse()
+	}
+
+	if e.discordBaseURL != "" {
+		u, err := parseDiscordEndpoint(e.discordBaseURL)
+		if err != nil {
+			e.log.Error("Invalid discord endpoint, using the default endpoint", "error", err)
+		} else {
+			e.discordEndpoint = u
+		}
+	}
+
+	if e.ackStatusCode < 200 || e.ackStatusCode > 299 {
+		e.log.Error("Ack status code must be a 2xx, using 202", "status", e.ackStatusCode)
+		e.ackStatusCode = http.StatusAccepted
+	}
+
+	if e.requireVerification && len(e.publicKey) == 0 {
+		e.log.Error("Verification is required but no public key is configured, all requests will fail")
+	}
+
+	if e.segmentPrefix != "" {
+		e.tracer = tracing.Prefixed(e.tracer, e.segmentPrefix)
+	}
+
+	e.sessionClient = e.newSessionClient()
+
+	return e
+}
+
+// Build finalises the underlying router, registering the endpoint's commands with it. It is called when the first
+// interaction is routed, so that options and commands can be configured in any order, but can be called during
+// initialisation to avoid the cost on the first invocation. Commands registered after the router is built are
+// registered with it immediately.
+func (e *Endpoint) Build() *Endpoint {
+	e.routerOnce.Do(func() {
+		// the router is created when it is first used so that it uses the configured logger
+		if e.router == nil {
+			e.router = router.New(router.WithLogger(e.log))
+		}
+
+		for k, h := range e.handlers {
+			e.router.RegisterCommand(k.name, k.commandType, e.commandHandler(h, e.commands[k]))
+		}
+
+		e.built = true
+	})
+
 	return e
 }
 
 type Option func(*Endpoint)
 
-// WithRouter overrides the underlying router used for the endpoint.
+// WithRouter overrides the underlying router used for the endpoint. The router's logger is not changed by WithLogger.
+// Commands registered with the endpoint are registered with the router when it is built, see Build.
 func WithRouter(router *router.Router) Option {
 	return func(endpoint *Endpoint) {
 		endpoint.router = router
@@ -62,12 +180,157 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithDeferredResponseEnabled sends a deferred response as soon as an application command or message component
+// interaction is received, before it is handled. Commands are deferred with the deferred response (see
+// WithDeferredResponse), and components with a deferred message update, which shows a loading state on the component
+// rather than sending a new message. Messages returned by handlers are sent by editing the deferred response, except
+// new messages (type 4) returned by component handlers, which are sent as follow-ups.
 func WithDeferredResponseEnabled(enabled bool) Option {
 	return func(endpoint *Endpoint) {
 		endpoint.deferredResponseEnabled = enabled
 	}
 }
 
+// WithTracer overrides the tracer used by the endpoint, which defaults to tracing.XRay. The tracer is also passed to
+// session providers via the context.
+func WithTracer(t tracing.Tracer) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.tracer = t
+	}
+}
+
+// WithTracingDisabled disables tracing entirely, including the instrumentation of HTTP clients.
+func WithTracingDisabled() Option {
+	return WithTracer(tracing.Noop())
+}
+
+// WithSegmentPrefix prefixes the names of the segments (or spans) created by the endpoint and its session providers,
+// e.g. "bot-a " to distinguish the traces of multiple endpoints in one service.
+func WithSegmentPrefix(prefix string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.segmentPrefix = prefix
+	}
+}
+
+// WithHTTPClient overrides the HTTP client used by the interaction-scoped session. The client is instrumented by the
+// endpoint's tracer.
+func WithHTTPClient(client *http.Client) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.httpClient = client
+	}
+}
+
+// WithDeferredResponse enables deferred responses (see WithDeferredResponseEnabled), replacing the default ephemeral
+// deferred response with the one provided. The response must be a deferred channel message (type 5), as it is used to
+// defer application commands, and modal submissions which exceed the defer timeout (see WithDeferOnTimeout). Any other
+// response is logged and the default is used instead.
+func WithDeferredResponse(res *discordgo.InteractionResponse) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.deferredResponseEnabled = true
+		endpoint.deferredResponse = res
+	}
+}
+
+// defaultDeferredResponse is an ephemeral deferred channel message
+func defaultDeferredResponse() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}
+}
+
+// UnhandledInteractionHandler handles interactions for which no handler is registered, returning an optional response.
+type UnhandledInteractionHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.InteractionResponse
+
+// WithUnhandledInteractionHandler sets a handler which is called instead of the router when an interaction is received
+// for which no handler is registered with the endpoint, such as a stale command registration. A warning is always
+// logged for unhandled interactions.
+// Note that commands registered directly with a Router provided by WithRouter are not known to the endpoint, and so
+// are treated as unhandled.
+func WithUnhandledInteractionHandler(h UnhandledInteractionHandler) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.unhandled = h
+	}
+}
+
+// InteractionTypeHandler handles every interaction of a type, returning an optional response.
+type InteractionTypeHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.InteractionResponse
+
+// WithInteractionTypeHandler sets a catch-all handler for interactions of the type, such as all message components,
+// which is called when no more specific handler (such as a command or component handler) is registered for the
+// interaction. It takes precedence over the handler set by WithUnhandledInteractionHandler.
+func WithInteractionTypeHandler(t discordgo.InteractionType, h InteractionTypeHandler) Option {
+	return func(endpoint *Endpoint) {
+		if endpoint.typeHandlers == nil {
+			endpoint.typeHandlers = map[discordgo.InteractionType]InteractionTypeHandler{}
+		}
+
+		endpoint.typeHandlers[t] = h
+	}
+}
+
+// defaultMaxBodySize is well above the size of any interaction sent by Discord
+const defaultMaxBodySize = 4 << 20
+
+// WithMaxBodySize rejects requests with a body larger than n bytes with a 413, before the body is verified or decoded.
+// Defaults to 4MiB. Use 0 to accept bodies of any size.
+func WithMaxBodySize(n int) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.maxBodySize = n
+	}
+}
+
+// WithAckStatusCode sets the status code returned when an interaction is handled without a response, e.g. 200 for
+// integrations which do not accept a 202. Defaults to 202. Codes which are not a 2xx are logged and the default is used
+// instead.
+func WithAckStatusCode(code int) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.ackStatusCode = code
+	}
+}
+
+// PreFilter decides whether a request should be processed, returning the status code to respond with if it is not.
+// Rejected requests are responded to with 403 Forbidden if the status code is not an error status (4xx or 5xx).
+type PreFilter func(headers map[string]string, body []byte) (allow bool, status int)
+
+// WithPreFilter adds a filter which is called before the request is verified, allowing obviously bad traffic to be
+// dropped without spending time on signature verification.
+func WithPreFilter(f PreFilter) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.preFilter = f
+	}
+}
+
+// SignatureHeaderParser parses the signature and timestamp from a combined signature header value.
+type SignatureHeaderParser func(value string) (sig, ts string, err error)
+
+// WithCombinedSignatureHeader reads both the signature and timestamp from a single header, for setups where a proxy
+// collapses the standard X-Signature-Ed25519 and X-Signature-Timestamp headers into one.
+// See ParseAuthorizationSignature for a parser for the "Ed25519 <sig>.<ts>" format.
+func WithCombinedSignatureHeader(name string, parse SignatureHeaderParser) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.signatureHeader = name
+		endpoint.parseSignatureHeader = parse
+	}
+}
+
+// ParseAuthorizationSignature parses a combined signature header value in the format "Ed25519 <sig>.<ts>".
+func ParseAuthorizationSignature(value string) (sig, ts string, err error) {
+	scheme, credentials, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(scheme, "Ed25519") {
+		return "", "", errors.New("expected Ed25519 scheme")
+	}
+
+	sig, ts, ok = strings.Cut(credentials, ".")
+	if !ok || sig == "" || ts == "" {
+		return "", "", errors.New("expected <sig>.<ts>")
+	}
+
+	return sig, ts, nil
+}
+
 // WithSessionProvider adds a provider which will be called before each handler invocation to override the interaction's
 // default session (created using the interaction's token).
 // This is useful in scenarios where the bot requires more permissions than is provided by the token provided by the
@@ -85,6 +348,20 @@ func (e *Endpoint) WithSession(s *discordgo.Session) *Endpoint {
 	return e
 }
 
+// WithSessionProviderOption configures the session provider when the endpoint is created. See WithSessionProvider.
+func WithSessionProviderOption(f sessionprovider.Provider) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.WithSessionProvider(f)
+	}
+}
+
+// WithSessionOption configures a hardcoded global session when the endpoint is created. See WithSession.
+func WithSessionOption(s *discordgo.Session) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.WithSession(s)
+	}
+}
+
 // WithChatApplicationCommand registers a new discordgo.ChatApplicationCommand.
 // This is syntactic sugar for WithApplicationCommand
 func (e *Endpoint) WithChatApplicationCommand(name string, handler router.ApplicationCommandHandler) *Endpoint {
@@ -104,92 +381,271 @@ func (e *Endpoint) WithMessageApplicationCommand(name string, handler router.App
 }
 
 // WithApplicationCommand registers a new application command with the underlying Router.
+// A warning is logged if the name is invalid (see ValidateCommandName).
 func (e *Endpoint) WithApplicationCommand(name string, commandType discordgo.ApplicationCommandType, handler router.ApplicationCommandHandler) *Endpoint {
-	e.router.RegisterCommand(name, commandType, handler)
+	return e.WithApplicationCommandOptions(name, commandType, handler, CommandOptions{})
+}
 
-	return e
+// WithApplicationCommandDeferred registers a new application command which is sent a deferred response before it is
+// handled, regardless of WithDeferredResponseEnabled. The deferred response is ephemeral, as with the endpoint's default.
+func (e *Endpoint) WithApplicationCommandDeferred(name string, commandType discordgo.ApplicationCommandType, handler router.ApplicationCommandHandler) *Endpoint {
+	return e.WithApplicationCommandOptions(name, commandType, handler, CommandOptions{Deferred: true, Ephemeral: true})
 }
 
-// HandleEvent is the lambda handler for events.APIGatewayProxyRequest (when the lambda function is integrated with API
-// Gateway.
-// See https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-lambda-proxy-integrations.html for more info.
-func (e *Endpoint) HandleEvent(ctx context.Context, event *events.APIGatewayProxyRequest) (res *events.APIGatewayProxyResponse, err error) {
-	ctx, s := xray.BeginSubsegment(ctx, "handle event")
-	defer s.Close(err)
+// WithApplicationCommandOptions registers a new application command with the underlying Router, with options which
+// configure how the endpoint handles it. See WithApplicationCommand.
+func (e *Endpoint) WithApplicationCommandOptions(name string, commandType discordgo.ApplicationCommandType, handler router.ApplicationCommandHandler, options CommandOptions) *Endpoint {
+	if err := ValidateCommandName(name, commandType); err != nil {
+		e.log.Warn("Registering command with invalid name", "error", err)
+	}
 
-	if event.RequestContext.HTTPMethod != http.MethodPost {
-		// Receiving anything other than a POST requests points to a configuration issue and should be investigated
-		e.log.Error("Unexpected http method", slog.String("method", event.RequestContext.HTTPMethod))
-		return &events.APIGatewayProxyResponse{StatusCode: http.StatusMethodNotAllowed}, nil
+	k := commandKey{name: name, commandType: commandType}
+	if _, ok := e.handlers[k]; ok {
+		e.log.Warn("Overwriting duplicate command registration", "name", name, "type", commandType)
 	}
 
-	e.log.Debug("Received event")
+	e.commands[k] = options
+	e.handlers[k] = handler
+
+	if e.built {
+		e.router.RegisterCommand(name, commandType, e.commandHandler(handler, options))
+	}
 
-	body, code, err := e.handle(ctx, event.Headers, []byte(event.Body))
+	return e
+}
 
+// HandleEvent is the lambda handler for events.APIGatewayProxyRequest (when the lambda function is integrated with API
+// Gateway.
+// See https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-lambda-proxy-integrations.html for more info.
+func (e *Endpoint) HandleEvent(ctx context.Context, event *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	res, err := e.serve(ctx, "handle event", httpRequest{
+		method:    event.RequestContext.HTTPMethod,
+		headers:   event.Headers,
+		body:      event.Body,
+		base64:    event.IsBase64Encoded,
+		requestID: event.RequestContext.RequestID,
+		sourceIP:  event.RequestContext.Identity.SourceIP,
+		userAgent: event.RequestContext.Identity.UserAgent,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &events.APIGatewayProxyResponse{
-		StatusCode: code,
-		Body:       body,
+		StatusCode:      res.code,
+		Headers:         res.headers,
+		Body:            res.body,
+		IsBase64Encoded: res.base64,
 	}, nil
 }
 
 // HandleRequest handles the events.LambdaFunctionURLRequest.
 // It should be registered to the Lambda Start in a function which is configured as a single-url function.
 // See https://docs.aws.amazon.com/lambda/latest/dg/urls-configuration.html for more info.
-func (e *Endpoint) HandleRequest(ctx context.Context, event *events.LambdaFunctionURLRequest) (res *events.LambdaFunctionURLResponse, err error) {
-	ctx, s := xray.BeginSubsegment(ctx, "handle request")
-	defer s.Close(err)
+func (e *Endpoint) HandleRequest(ctx context.Context, event *events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLResponse, error) {
+	res, err := e.serve(ctx, "handle request", httpRequest{
+		method:    event.RequestContext.HTTP.Method,
+		headers:   event.Headers,
+		body:      event.Body,
+		base64:    event.IsBase64Encoded,
+		requestID: event.RequestContext.RequestID,
+		sourceIP:  event.RequestContext.HTTP.SourceIP,
+		userAgent: event.RequestContext.HTTP.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &events.LambdaFunctionURLResponse{
+		StatusCode:      res.code,
+		Headers:         res.headers,
+		Body:            res.body,
+		IsBase64Encoded: res.base64,
+	}, nil
+}
+
+// HandleV2 handles the events.APIGatewayV2HTTPRequest (when the lambda function is integrated with an API Gateway HTTP
+// API using the 2.0 payload format).
+// See https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-develop-integrations-lambda.html for more info.
+func (e *Endpoint) HandleV2(ctx context.Context, event *events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+	res, err := e.serve(ctx, "handle v2", httpRequest{
+		method:    event.RequestContext.HTTP.Method,
+		headers:   event.Headers,
+		body:      event.Body,
+		base64:    event.IsBase64Encoded,
+		requestID: event.RequestContext.RequestID,
+		sourceIP:  event.RequestContext.HTTP.SourceIP,
+		userAgent: event.RequestContext.HTTP.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &events.APIGatewayV2HTTPResponse{
+		StatusCode:      res.code,
+		Headers:         res.headers,
+		Body:            res.body,
+		IsBase64Encoded: res.base64,
+	}, nil
+}
+
+// httpRequest is the part of a Lambda HTTP event which is common to each of the supported integrations
+type httpRequest struct {
+	method, body                   string
+	headers                        map[string]string
+	base64                         bool
+	requestID, sourceIP, userAgent string
+}
+
+// httpResponse is the part of a Lambda HTTP response which is common to each of the supported integrations
+type httpResponse struct {
+	code    int
+	headers map[string]string
+	body    string
+	base64  bool
+}
+
+// serve handles the request received by one of the HTTP integrations, tracing it with a span of the name
+func (e *Endpoint) serve(ctx context.Context, name string, req httpRequest) (res httpResponse, err error) {
+	ctx, s := e.startSpan(e.tracer.Extract(ctx, req.headers), name)
+	defer func() { s.End(err) }()
+
+	ctx = e.withRequestID(ctx, req.requestID)
+
+	if headers, ok := e.preflight(req.method, req.headers); ok {
+		return httpResponse{code: http.StatusNoContent, headers: headers}, nil
+	}
 
-	if event.RequestContext.HTTP.Method != http.MethodPost {
-		// Receiving anything other than a POST requests points to a configuration issue and should be investigated
-		e.log.Error("Unexpected http method", slog.String("method", event.RequestContext.HTTP.Method))
-		return &events.LambdaFunctionURLResponse{StatusCode: http.StatusMethodNotAllowed}, nil
+	if req.method != http.MethodPost {
+		code, body := e.unexpectedMethod(ctx, req.method)
+		return httpResponse{code: code, body: body}, nil
 	}
 
-	e.log.Debug(
+	e.logger(ctx).Debug(
 		"Received request",
-		slog.String("user_agent", event.RequestContext.HTTP.UserAgent),
+		slog.String("user_agent", req.userAgent),
 	)
 
-	body, code, err := e.handle(ctx, event.Headers, []byte(event.Body))
+	ctx = withRequestInfo(ctx, requestInfo{
+		sourceIP:  req.sourceIP,
+		userAgent: req.userAgent,
+	})
 
+	reqBody, err := requestBody(req.body, req.base64)
 	if err != nil {
-		return nil, err
+		e.logger(ctx).Warn("Invalid request body", "error", err)
+		return httpResponse{code: http.StatusBadRequest}, nil
 	}
 
-	return &events.LambdaFunctionURLResponse{
-		StatusCode: code,
-		Body:       body,
-	}, nil
+	ctx, resHeaders := withResponseHeaders(ctx)
+
+	body, code, err := e.handle(ctx, req.headers, reqBody)
+	if err != nil {
+		return httpResponse{}, err
+	}
+
+	body, headers, encoded, err := e.encodeResponse(req.headers, body)
+	if err != nil {
+		return httpResponse{}, err
+	}
+
+	headers = e.withCORSHeaders(req.headers, headers)
+	headers = resHeaders.apply(headers)
+
+	return httpResponse{code: code, headers: headers, body: body, base64: encoded}, nil
 }
 
 func (e *Endpoint) handle(ctx context.Context, headers map[string]string, body []byte) (res string, code int, err error) {
-	ctx, s := xray.BeginSubsegment(ctx, "handle")
-	defer s.Close(err)
+	ctx, s := e.startSpan(ctx, "handle")
+	defer func() { s.End(err) }()
+
+	ctx = e.correlate(ctx, headers, s)
+
+	if e.preFilter != nil {
+		if allow, status := e.preFilter(headers, body); !allow {
+			if status < http.StatusBadRequest || status > 599 {
+				status = http.StatusForbidden
+			}
+			e.logger(ctx).Debug("Request rejected by pre-filter", slog.Int("status", status))
+			return "", status, nil
+		}
+	}
+
+	body, err = e.decompress(headers, body)
+	if errors.Is(err, errBodyTooLarge) {
+		e.logger(ctx).Warn("Received oversized request body")
+		return "", http.StatusRequestEntityTooLarge, nil
+	}
+	if err != nil {
+		e.logger(ctx).Warn("Invalid request body", "error", err)
+		return "", http.StatusBadRequest, nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		e.logger(ctx).Warn("Received empty request body")
+		return "", http.StatusBadRequest, nil
+	}
+
+	if e.maxBodySize > 0 && len(body) > e.maxBodySize {
+		e.logger(ctx).Warn("Received oversized request body", slog.Int("size", len(body)))
+		return "", http.StatusRequestEntityTooLarge, nil
+	}
+
+	if e.requireVerification && len(e.publicKey) == 0 {
+		return "", 0, ErrNoPublicKey
+	}
 
 	if err = e.verify(ctx, headers, body); err != nil {
-		e.log.Error("Failed to verify signature", "error", err)
+		e.verificationFailed(ctx, headers, err)
 		return "", http.StatusUnauthorized, nil
 	}
+	ctx = context.WithValue(ctx, verifiedKey{}, len(e.publicKey) > 0)
 
-	var i *discordgo.InteractionCreate
-	if err = json.Unmarshal(body, &i); err != nil {
+	env := readEnvelope(body)
+	if env.isWebhookEvent() {
+		if err = e.handleWebhookEvent(ctx, body); err != nil {
+			return "", 0, err
+		}
+
+		// webhook events are acknowledged with a 204
+		// https://discord.com/developers/docs/events/webhook-events#responding-to-an-event
+		return "", http.StatusNoContent, nil
+	}
+
+	i, err := e.decode(body)
+	if err != nil {
 		return "", 0, fmt.Errorf("unmarshal interaction create: %w", err)
 	}
+	if i == nil || i.Interaction == nil {
+		e.logger(ctx).Warn("Received empty interaction")
+		return "", http.StatusBadRequest, nil
+	}
+
+	if !e.applicationAllowed(i) {
+		e.logger(ctx).Warn("Interaction for unexpected application", "application_id", i.AppID)
+		return "", http.StatusForbidden, nil
+	}
+
+	ctx = withEnvelope(ctx, env)
+	ctx = withRequestBody(ctx, body)
 
 	response, err := e.handleInteraction(ctx, i)
+	if isSessionError(err) {
+		return "", e.sessionFailed(ctx, err), nil
+	}
 	if err != nil {
 		return "", 0, err
 	}
 
-	// if no response is provided then return a 202
+	// if no response is provided then acknowledge the interaction, with a 202 by default
 	//https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-callback
 	if response == nil {
-		return "", http.StatusAccepted, nil
+		return "", e.ackStatusCode, nil
+	}
+
+	if err = ValidateResponse(i, response); err != nil {
+		e.logger(ctx).Error("Invalid interaction response", "interaction_type", i.Type, "response_type", response.Type, "error", err)
+		return "", 0, fmt.Errorf("invalid interaction response: %w", err)
 	}
 
 	bs, err := json.Marshal(response)
@@ -200,11 +656,16 @@ func (e *Endpoint) handle(ctx context.Context, headers map[string]string, body [
 	return string(bs), http.StatusOK, err
 }
 
+// startSpan starts a span using the endpoint's tracer, adding the tracer to the context for use by session providers
+func (e *Endpoint) startSpan(ctx context.Context, name string) (context.Context, tracing.Span) {
+	return e.tracer.StartSpan(tracing.ContextWithTracer(ctx, e.tracer), name)
+}
+
 // verify verifies the request using the ed25519 signature as per Discord's documentation.
 // See https://discord.com/developers/docs/events/webhook-events#setting-up-an-endpoint-validating-security-request-headers.
 func (e *Endpoint) verify(ctx context.Context, headers map[string]string, body []byte) error {
-	_, s := xray.BeginSubsegment(ctx, "verify")
-	defer s.Close(nil)
+	_, s := e.startSpan(ctx, "verify")
+	defer s.End(nil)
 
 	// if no public key is provided then skip verification
 	if len(e.publicKey) == 0 {
@@ -216,73 +677,335 @@ func (e *Endpoint) verify(ctx context.Context, headers map[string]string, body [
 		parsed.Add(k, v)
 	}
 
-	signature := parsed.Get(headerSignature)
-	if signature == "" {
-		return errors.New("missing header X-Signature-Ed25519")
-	}
-	ts := parsed.Get(headerTimestamp)
-	if ts == "" {
-		return errors.New("missing header X-Signature-Timestamp")
+	signature, ts, err := e.signatureHeaders(parsed)
+	if err != nil {
+		return err
 	}
 
-	sig, err := hex.DecodeString(signature)
+	sig, err := e.signatureEncoding.decode(signature)
 	if err != nil {
-		return fmt.Errorf("invalid signature: %w", err)
+		return verificationError(ReasonBadEncoding, fmt.Errorf("invalid signature: %w", err))
 	}
 
 	verify := append([]byte(ts), body...)
 
 	if !ed25519.Verify(e.publicKey, verify, sig) {
-		return errors.New("invalid signature")
+		return verificationError(ReasonInvalidSignature, errors.New("invalid signature"))
 	}
 
-	return nil
+	if err = e.checkTimestamp(ts); err != nil {
+		return err
+	}
+
+	return e.checkReplay(ctx, ts, sig)
+}
+
+// signatureHeaders returns the signature and timestamp from the request headers.
+func (e *Endpoint) signatureHeaders(headers http.Header) (signature, ts string, err error) {
+	if e.signatureHeader != "" {
+		v := headers.Get(e.signatureHeader)
+		if v == "" {
+			return "", "", verificationError(ReasonMissingSignature, fmt.Errorf("missing header %s", e.signatureHeader))
+		}
+
+		signature, ts, err = e.parseSignatureHeader(v)
+		if err != nil {
+			return "", "", verificationError(ReasonMissingSignature, fmt.Errorf("parse header %s: %w", e.signatureHeader, err))
+		}
+
+		return signature, ts, nil
+	}
+
+	signature = headers.Get(headerSignature)
+	if signature == "" {
+		return "", "", verificationError(ReasonMissingSignature, errors.New("missing header X-Signature-Ed25519"))
+	}
+	ts = headers.Get(headerTimestamp)
+	if ts == "" {
+		return "", "", verificationError(ReasonMissingTimestamp, errors.New("missing header X-Signature-Timestamp"))
+	}
+
+	return signature, ts, nil
 }
 
 // handleInteraction handles the discordgo.InteractionCreate, returning an optional sync response
 func (e *Endpoint) handleInteraction(ctx context.Context, i *discordgo.InteractionCreate) (res *discordgo.InteractionResponse, err error) {
-	log := e.log.With("interaction_type", i.Type, "interaction_id", i.ID)
-	log.Debug("Handling interaction")
-	ctx, seg := xray.BeginSubsegment(ctx, "handle interaction")
-	_ = seg.AddAnnotation("type", int(i.Type))
-	defer seg.Close(err)
+	if i.Type == discordgo.InteractionPing {
+		return e.pong(), nil
+	}
 
-	var s *discordgo.Session
+	log := e.logger(ctx).With("interaction_type", i.Type, "interaction_id", i.ID)
+
+	// acknowledge interaction types added to the protocol since this package was written, rather than failing
+	if !knownInteractionType(i.Type) {
+		log.Warn("Unknown interaction type", "type", int(i.Type))
+		return nil, nil
+	}
+
+	log.Debug("Handling interaction")
+	e.logInteraction(ctx, i)
+	ctx = withResponseGuard(ctx)
+	ctx, seg := e.startSpan(ctx, "handle interaction")
+	annotate(seg, i)
+	defer func() { seg.End(err) }()
 
 	// build a session scoped for the interaction
-	s, _ = discordgo.New("Bot " + i.Token)
-	s.Client = xray.Client(s.Client)
+	s := e.interactionSession(i)
 
 	// if deferred response is enabled, then respond to the interaction ASAP
-	if e.deferredResponseEnabled && i.Type == discordgo.InteractionApplicationCommand {
+	deferred := e.deferredResponseFor(i)
+	if deferred != nil {
 		log.Debug("Sending deferred response")
-		if err := e.sendDeferredResponse(ctx, i, s); err != nil {
+		if err := e.sendDeferredResponse(ctx, i, s, deferred); err != nil {
 			return nil, fmt.Errorf("sending deferred response: %w", err)
 		}
+
+		if e.deferQueue != nil {
+			log.Debug("Enqueuing interaction")
+			return nil, e.enqueue(ctx, i)
+		}
 	}
 
-	// if a session provider exists then resolve it to use it as the session source
-	if e.s != nil {
+	// if the interaction has not been deferred then it can be deferred if the handler is slow to respond
+	if deferred == nil && e.deferOnTimeout > 0 {
+		res, err = e.dispatchOrDefer(ctx, s, i)
+	} else {
+		res, err = e.dispatch(ctx, s, i)
+	}
+
+	if deferred != nil && err == nil && res != nil {
+		// the interaction has been deferred, so the handler's message is sent by editing the deferred response or as a
+		// follow-up
+		return nil, e.completeDeferredResponse(ctx, s, i, deferred, res)
+	}
+
+	if err == nil && res != nil && !markResponded(ctx) {
+		// the interaction was responded to by the endpoint or the handler, so the response cannot be sent
+		return nil, fmt.Errorf("%w: discarding response of type %d", ErrAlreadyResponded, res.Type)
+	}
+
+	return res, err
+}
+
+// dispatch resolves the session and passes the interaction through the middleware chain to the router
+func (e *Endpoint) dispatch(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+	ctx, cancel := e.handlerContext(ctx)
+	defer cancel()
+	defer e.checkDeadline(ctx)
+
+	ctx = context.WithValue(ctx, tokenKey{}, i.Token)
+	ctx = e.withLocale(ctx, i)
+
+	res, err := chain(e.preSessionMiddleware, i.Type, e.resolveSession)(ctx, s, i)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.applyDefaults(res), nil
+}
+
+// resolveSession resolves the session before passing the interaction through the middleware chain to the router
+func (e *Endpoint) resolveSession(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+	// if a session provider exists then resolve it to use it as the session source, unless requests are being recorded
+	if e.s != nil && e.dryRun == nil {
 		var err error
-		s, err = e.s(ctx)
+		s, err = e.provideSession(sessionprovider.WithInteraction(ctx, i))
 		if err != nil {
-			return nil, fmt.Errorf("get session from source: %w", err)
+			return nil, err
 		}
 	}
 
-	return e.router.HandleWithContext(ctx, s, i), nil
+	ctx = context.WithValue(ctx, sessionKey{}, s)
+
+	return chain(e.middleware, i.Type, e.route)(ctx, s, i)
 }
 
-func (e *Endpoint) sendDeferredResponse(ctx context.Context, i *discordgo.InteractionCreate, s *discordgo.Session) (err error) {
-	ctx, seg := xray.BeginSubsegment(ctx, "send deferred response")
+// route dispatches the interaction to the router, or to the interaction type or unhandled interaction handler if the
+// endpoint has no handler registered for it
+func (e *Endpoint) route(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+	if h, params, ok := e.componentHandler(i); ok {
+		return h(ctx, s, i, params)
+	}
 
-	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Flags: discordgo.MessageFlagsEphemeral,
-		},
-	}, discordgo.WithContext(ctx))
+	// the router only handles commands, and responds to other interactions with a message, which autocomplete
+	// interactions do not accept
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete && e.handles(i) {
+		return AutocompleteResponse(), nil
+	}
+
+	if !e.handles(i) {
+		if h, ok := e.typeHandlers[i.Type]; ok {
+			return h(ctx, s, i), nil
+		}
+
+		e.logger(ctx).Warn("Unhandled interaction", "interaction_type", i.Type, "interaction_id", i.ID)
+
+		if e.unhandled != nil {
+			return e.unhandled(ctx, s, i), nil
+		}
+	}
+
+	// autocomplete interactions for unregistered commands which the unhandled interaction handler did not answer
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return AutocompleteResponse(), nil
+	}
+
+	return e.Build().router.HandleWithContext(ctx, s, i), nil
+}
 
-	seg.Close(err)
+// provideSession resolves the session from the session provider
+func (e *Endpoint) provideSession(ctx context.Context) (*discordgo.Session, error) {
+	s, err := e.s(ctx)
+	if err != nil {
+		return nil, &sessionError{err: fmt.Errorf("get session from source: %w", err)}
+	}
+	if s == nil {
+		e.logger(ctx).Error("Session provider returned a nil session")
+		return nil, &sessionError{err: sessionprovider.Permanent(errNilSession)}
+	}
+
+	return s, nil
+}
+
+// knownInteractionType returns true if the interaction type is one the endpoint can handle
+func knownInteractionType(t discordgo.InteractionType) bool {
+	switch t {
+	case discordgo.InteractionPing,
+		discordgo.InteractionApplicationCommand,
+		discordgo.InteractionMessageComponent,
+		discordgo.InteractionApplicationCommandAutocomplete,
+		discordgo.InteractionModalSubmit:
+		return true
+	default:
+		return false
+	}
+}
+
+// handles returns true if a handler is registered with the endpoint for the interaction
+func (e *Endpoint) handles(i *discordgo.InteractionCreate) bool {
+	switch i.Type {
+	case discordgo.InteractionPing:
+		return true
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		_, ok := e.commands[commandKey{name: data.Name, commandType: data.CommandType}]
+		return ok
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		// only chat commands have options which can be autocompleted
+		_, ok := e.commands[commandKey{name: i.ApplicationCommandData().Name, commandType: discordgo.ChatApplicationCommand}]
+		return ok
+	case discordgo.InteractionMessageComponent, discordgo.InteractionModalSubmit:
+		_, _, ok := e.componentHandler(i)
+		return ok
+	default:
+		return false
+	}
+}
+
+// annotate adds annotations to the segment to allow traces to be filtered by the interaction's properties
+func annotate(seg tracing.Span, i *discordgo.InteractionCreate) {
+	seg.SetAttribute("type", int(i.Type))
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		seg.SetAttribute("command", i.ApplicationCommandData().Name)
+	}
+
+	if i.GuildID != "" {
+		seg.SetAttribute("guild_id", i.GuildID)
+	}
+	if i.ChannelID != "" {
+		seg.SetAttribute("channel_id", i.ChannelID)
+	}
+}
+
+// deferredResponseFor returns the deferred response to send for the interaction, or nil if it should not be deferred
+func (e *Endpoint) deferredResponseFor(i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		if options := e.commands[commandKey{name: data.Name, commandType: data.CommandType}]; options.Deferred {
+			return options.deferredResponse()
+		}
+
+		if e.deferredResponseEnabled {
+			return e.deferredResponse
+		}
+	case discordgo.InteractionMessageComponent:
+		// components are deferred with a message update, as a deferred message would send a new message. Only commands
+		// are enqueued to the defer queue.
+		if e.deferredResponseEnabled && e.deferQueue == nil {
+			return AckResponse()
+		}
+	}
+
+	return nil
+}
+
+func (e *Endpoint) sendDeferredResponse(ctx context.Context, i *discordgo.InteractionCreate, s *discordgo.Session, res *discordgo.InteractionResponse) (err error) {
+	ctx, seg := e.startSpan(ctx, "send deferred response")
+
+	if !markResponded(ctx) {
+		seg.End(ErrAlreadyResponded)
+		return ErrAlreadyResponded
+	}
+
+	err = e.respond(ctx, s, i, res)
+	if err != nil {
+		e.deferredResponseFailed(ctx, seg, err)
+	}
+
+	seg.End(err)
 	return
 }
+
+// respond sends the response to the interaction. If the callback is rate limited then it is retried once after the
+// Retry-After duration, provided that the retry would complete before the context's deadline.
+func (e *Endpoint) respond(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, res *discordgo.InteractionResponse) error {
+	// rate limits are handled here rather than by discordgo, which retries indefinitely
+	err := s.InteractionRespond(i.Interaction, res, discordgo.WithContext(ctx), discordgo.WithRetryOnRatelimit(false))
+
+	var rateLimited *discordgo.RateLimitError
+	if !errors.As(err, &rateLimited) {
+		return err
+	}
+
+	retryAfter := rateLimited.RetryAfter
+	log := e.logger(ctx).With("interaction_id", i.ID, "retry_after", retryAfter)
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(retryAfter).After(deadline) {
+		log.Warn("Interaction callback rate limited, not retrying as the retry would exceed the deadline")
+		return err
+	}
+
+	log.Warn("Interaction callback rate limited, retrying")
+
+	t := time.NewTimer(retryAfter)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return errors.Join(err, ctx.Err())
+	case <-t.C:
+	}
+
+	return s.InteractionRespond(i.Interaction, res, discordgo.WithContext(ctx), discordgo.WithRetryOnRatelimit(false))
+}
+
+// deferredResponseFailed records the failure of the deferred response, including the status and body of the callback
+// response if Discord rejected it, e.g. with a 401 for a bad token or a 404 for an expired interaction
+func (e *Endpoint) deferredResponseFailed(ctx context.Context, seg tracing.Span, err error) {
+	e.metrics.Count(MetricDeferredResponseFailed, 1)
+
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		e.logger(ctx).Error("Failed to send deferred response", "error", err)
+		return
+	}
+
+	seg.SetAttribute("callback_status", restErr.Response.StatusCode)
+	e.logger(ctx).Error(
+		"Deferred response rejected",
+		"status", restErr.Response.StatusCode,
+		"body", string(restErr.ResponseBody),
+	)
+}
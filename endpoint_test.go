@@ -0,0 +1,155 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuthorizationSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		sig, ts string
+		wantErr bool
+	}{
+		{name: "valid", value: "Ed25519 abc.123", sig: "abc", ts: "123"},
+		{name: "case insensitive scheme", value: "ed25519 abc.123", sig: "abc", ts: "123"},
+		{name: "missing scheme", value: "abc.123", wantErr: true},
+		{name: "wrong scheme", value: "Bearer abc.123", wantErr: true},
+		{name: "missing separator", value: "Ed25519 abc123", wantErr: true},
+		{name: "missing signature", value: "Ed25519 .123", wantErr: true},
+		{name: "missing timestamp", value: "Ed25519 abc.", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, ts, err := ParseAuthorizationSignature(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.sig, sig)
+			assert.Equal(t, tt.ts, ts)
+		})
+	}
+}
+
+func TestHandle_EmptyBody(t *testing.T) {
+	for _, body := range []string{"", "  \n", "null"} {
+		t.Run(fmt.Sprintf("%q", body), func(t *testing.T) {
+			e := New(nil, WithLogger(slogt.New(t)))
+
+			res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+				},
+				Body: body,
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		})
+	}
+}
+
+func TestWithMaxBodySize(t *testing.T) {
+	body := mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})
+
+	tests := []struct {
+		name string
+		max  int
+		code int
+	}{
+		{name: "under limit", max: len(body) + 1, code: http.StatusOK},
+		{name: "at limit", max: len(body), code: http.StatusOK},
+		{name: "over limit", max: len(body) - 1, code: http.StatusRequestEntityTooLarge},
+		{name: "unlimited", max: 0, code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(nil, WithLogger(slogt.New(t)), WithMaxBodySize(tt.max))
+
+			res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+				},
+				Body: string(body),
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestHandleV2(t *testing.T) {
+	e, calls := commandEndpoint(t)
+
+	request := func(method, body string) *events.APIGatewayV2HTTPResponse {
+		res, err := e.HandleV2(context.Background(), &events.APIGatewayV2HTTPRequest{
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				RequestID: "request_id",
+				HTTP:      events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: method},
+			},
+			Headers: map[string]string{"content-type": "application/json"},
+			Body:    body,
+		})
+		require.NoError(t, err)
+
+		return res
+	}
+
+	// when a ping is received then it is acknowledged
+	res := request(http.MethodPost, string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"type": 1}`, res.Body)
+
+	// when a command is received then it is handled
+	res = request(http.MethodPost, string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})))
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+
+	// when a request with an unexpected method is received then it is rejected
+	res = request(http.MethodGet, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
+
+func TestWithAckStatusCode(t *testing.T) {
+	e, calls := commandEndpoint(t, WithAckStatusCode(http.StatusOK))
+
+	// when the handler produces no response
+	res := send(t, e, fooCommand())
+
+	// then the configured status code should be returned
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Body)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWithAckStatusCode_Invalid(t *testing.T) {
+	for _, code := range []int{0, http.StatusMultipleChoices, http.StatusBadRequest} {
+		// given an endpoint with an ack status code which is not a 2xx
+		buf := &bytes.Buffer{}
+		e, _ := commandEndpoint(t, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithAckStatusCode(code))
+
+		// then the error should be logged and the default used
+		r := findRecord(logRecords(t, buf), "Ack status code must be a 2xx, using 202")
+		require.NotNil(t, r)
+		assert.EqualValues(t, code, r["status"])
+		assert.Equal(t, http.StatusAccepted, send(t, e, fooCommand()).StatusCode)
+	}
+}
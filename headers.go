@@ -0,0 +1,97 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrProtectedHeader is returned by SetResponseHeader for headers which are set by the endpoint, such as Content-Type.
+var ErrProtectedHeader = errors.New("response header is protected")
+
+// ErrNoResponseHeaders is returned by SetResponseHeader when the interaction is not being handled for a request which
+// has a response, e.g. when it was received from SQS.
+var ErrNoResponseHeaders = errors.New("interaction has no response headers")
+
+// protectedHeaders are the headers set by the endpoint which handlers cannot override
+var protectedHeaders = []string{"Content-Type", "Content-Encoding", "Content-Length", "Transfer-Encoding", "Vary"}
+
+type responseHeadersKey struct{}
+
+// responseHeaders holds the headers set by handlers for the response to the request being handled
+type responseHeaders struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// withResponseHeaders adds response headers to the context which can be set by handlers
+func withResponseHeaders(ctx context.Context) (context.Context, *responseHeaders) {
+	h := &responseHeaders{values: map[string]string{}}
+
+	return context.WithValue(ctx, responseHeadersKey{}, h), h
+}
+
+// SetResponseHeader sets a header on the HTTP response to the request being handled, e.g. for caching layers or API
+// Gateway response mapping. Headers set by the endpoint, such as Content-Type and CORS headers, cannot be overridden.
+// Headers set after the response has been returned, e.g. by a handler which was deferred, are ignored. Lambda@Edge
+// responses do not include handler headers.
+func SetResponseHeader(ctx context.Context, key, value string) error {
+	h, ok := ctx.Value(responseHeadersKey{}).(*responseHeaders)
+	if !ok {
+		return ErrNoResponseHeaders
+	}
+
+	key = http.CanonicalHeaderKey(key)
+	if isProtectedHeader(key) {
+		return fmt.Errorf("%w: %s", ErrProtectedHeader, key)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values[key] = value
+
+	return nil
+}
+
+// isProtectedHeader returns true if the canonical header key is set by the endpoint
+func isProtectedHeader(key string) bool {
+	return slices.Contains(protectedHeaders, key) || strings.HasPrefix(key, "Access-Control-")
+}
+
+// apply adds the headers set by handlers to the response headers
+func (h *responseHeaders) apply(headers map[string]string) map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.values) == 0 {
+		return headers
+	}
+
+	if headers == nil {
+		headers = make(map[string]string, len(h.values))
+	}
+	for k, v := range h.values {
+		headers[k] = v
+	}
+
+	return headers
+}
+
+// addVary adds the request header name to the Vary response header, keeping any names it already lists
+func addVary(headers map[string]string, name string) map[string]string {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	if v := headers["Vary"]; v != "" {
+		headers["Vary"] = v + ", " + name
+	} else {
+		headers["Vary"] = name
+	}
+
+	return headers
+}
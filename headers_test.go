@@ -0,0 +1,53 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetResponseHeader(t *testing.T) {
+	var errs []error
+	e, _ := commandEndpoint(t, WithMiddleware(func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			errs = append(errs,
+				SetResponseHeader(ctx, "cache-control", "no-store"),
+				SetResponseHeader(ctx, "content-type", "text/plain"),
+				SetResponseHeader(ctx, "Access-Control-Allow-Origin", "*"),
+			)
+			return next(ctx, s, i)
+		}
+	}), WithMiddleware(responding(&discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello"},
+	})))
+
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+	require.NoError(t, err)
+
+	// then the handler's header should be included in the response
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "no-store", res.Headers["Cache-Control"])
+
+	// and protected headers should not be overridden
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrProtectedHeader)
+	assert.ErrorIs(t, errs[2], ErrProtectedHeader)
+	assert.Equal(t, "application/json", res.Headers["Content-Type"])
+	assert.NotContains(t, res.Headers, "Access-Control-Allow-Origin")
+}
+
+func TestSetResponseHeader_NoResponse(t *testing.T) {
+	assert.ErrorIs(t, SetResponseHeader(context.Background(), "Cache-Control", "no-store"), ErrNoResponseHeaders)
+}
@@ -0,0 +1,53 @@
+package bot_lambda
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Check reports whether the endpoint is ready to handle interactions, by resolving a session from the session provider
+// if one is configured. It is cheap enough to be used as a readiness probe, e.g. from a /healthz route, and does not
+// require a signed request.
+func (e *Endpoint) Check(ctx context.Context) (err error) {
+	ctx, s := e.startSpan(ctx, "check")
+	defer func() { s.End(err) }()
+
+	if e.s == nil {
+		return nil
+	}
+
+	_, err = e.provideSession(ctx)
+
+	return err
+}
+
+type healthCheckResponse struct {
+	status int
+	body   string
+}
+
+// WithHealthCheckResponse responds to requests with the method, such as GET health checks routed to the endpoint, with
+// the status and body instead of a 405. Requests with other methods than POST are otherwise logged as errors.
+func WithHealthCheckResponse(method string, status int, body string) Option {
+	return func(endpoint *Endpoint) {
+		if endpoint.healthChecks == nil {
+			endpoint.healthChecks = map[string]healthCheckResponse{}
+		}
+
+		endpoint.healthChecks[strings.ToUpper(method)] = healthCheckResponse{status: status, body: body}
+	}
+}
+
+// unexpectedMethod returns the response to a request with a method other than POST
+func (e *Endpoint) unexpectedMethod(ctx context.Context, method string) (status int, body string) {
+	if res, ok := e.healthChecks[method]; ok {
+		e.logger(ctx).Debug("Responding to health check", slog.String("method", method))
+		return res.status, res.body
+	}
+
+	// Receiving anything other than a POST requests points to a configuration issue and should be investigated
+	e.logger(ctx).Error("Unexpected http method", slog.String("method", method))
+	return http.StatusMethodNotAllowed, ""
+}
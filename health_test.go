@@ -0,0 +1,82 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(ctx context.Context) (*discordgo.Session, error)
+		wantErr  error
+	}{
+		{name: "no provider"},
+		{name: "healthy provider", provider: func(ctx context.Context) (*discordgo.Session, error) {
+			return &discordgo.Session{}, nil
+		}},
+		{name: "failing provider", provider: func(ctx context.Context) (*discordgo.Session, error) {
+			return nil, errors.New("unavailable")
+		}, wantErr: errors.New("get session from source: unavailable")},
+		{name: "nil session", provider: func(ctx context.Context) (*discordgo.Session, error) {
+			return nil, nil
+		}, wantErr: errNilSession},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(nil, WithLogger(slogt.New(t)))
+			if tt.provider != nil {
+				e.WithSessionProvider(tt.provider)
+			}
+
+			err := e.Check(context.Background())
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWithHealthCheckResponse(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := New(nil,
+		WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		WithHealthCheckResponse("get", http.StatusOK, "ok"),
+	)
+
+	// when a GET request is received
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	assert.NoError(t, err)
+
+	// then the configured health response should be returned without logging an error
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ok", res.Body)
+	assert.Nil(t, findRecord(logRecords(t, buf), "Unexpected http method"))
+
+	// and other methods should still be rejected
+	res, err = e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPut},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.NotNil(t, findRecord(logRecords(t, buf), "Unexpected http method"))
+}
@@ -0,0 +1,63 @@
+package bot_lambda
+
+import (
+	"context"
+)
+
+// InteractionContextType is the context in which an interaction was invoked, e.g. within a DM with a user-installed
+// app. It is not yet defined by discordgo.
+// See https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-object-interaction-context-types
+type InteractionContextType int
+
+const (
+	InteractionContextGuild          InteractionContextType = 0
+	InteractionContextBotDM          InteractionContextType = 1
+	InteractionContextPrivateChannel InteractionContextType = 2
+)
+
+// ApplicationIntegrationType is the type of installation through which an app was authorized to handle an interaction.
+// It is not yet defined by discordgo.
+// See https://discord.com/developers/docs/resources/application#application-object-application-integration-types
+type ApplicationIntegrationType int
+
+const (
+	ApplicationIntegrationGuildInstall ApplicationIntegrationType = 0
+	ApplicationIntegrationUserInstall  ApplicationIntegrationType = 1
+)
+
+type installationKey struct{}
+
+type installation struct {
+	Context *InteractionContextType               `json:"context"`
+	Owners  map[ApplicationIntegrationType]string `json:"authorizing_integration_owners"`
+}
+
+// InteractionContext returns the context in which the interaction being handled was invoked, if sent by Discord.
+func InteractionContext(ctx context.Context) (InteractionContextType, bool) {
+	v, ok := ctx.Value(installationKey{}).(installation)
+	if !ok || v.Context == nil {
+		return 0, false
+	}
+
+	return *v.Context, true
+}
+
+// IntegrationOwners returns the IDs of the guild or user which installed the app, keyed by installation type, for the
+// interaction being handled. The guild installation's ID is "0" if the interaction was invoked in a DM with the app's
+// bot user.
+// See https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-object-authorizing-integration-owners-object
+func IntegrationOwners(ctx context.Context) map[ApplicationIntegrationType]string {
+	v, _ := ctx.Value(installationKey{}).(installation)
+
+	return v.Owners
+}
+
+// withInstallation adds the installation context read from the interaction body to the context, as
+// discordgo.Interaction does not decode it
+func withInstallation(ctx context.Context, v installation) context.Context {
+	if v.Context == nil && len(v.Owners) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, installationKey{}, v)
+}
@@ -0,0 +1,111 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractionContext_UserInstall(t *testing.T) {
+	var interactionContext InteractionContextType
+	var found bool
+	var owners map[ApplicationIntegrationType]string
+	e, calls := commandEndpoint(t, WithMiddleware(func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			interactionContext, found = InteractionContext(ctx)
+			owners = IntegrationOwners(ctx)
+			return next(ctx, s, i)
+		}
+	}))
+
+	// given a command invoked by a user in a private channel through a user installation, without a guild or member
+	i := fooCommand()
+	i.ChannelID = "channel_id"
+	i.User = &discordgo.User{ID: "user_id"}
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(mustMarshal(t, &discordgo.InteractionCreate{Interaction: i}), &body))
+	body["context"] = 2
+	body["authorizing_integration_owners"] = map[string]string{"1": "user_id"}
+
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, body)),
+	})
+	require.NoError(t, err)
+
+	// then it should be handled
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+
+	// and the installation context should be available to the handler
+	assert.True(t, found)
+	assert.Equal(t, InteractionContextPrivateChannel, interactionContext)
+	assert.Equal(t, map[ApplicationIntegrationType]string{ApplicationIntegrationUserInstall: "user_id"}, owners)
+}
+
+func TestInteractionContext_Missing(t *testing.T) {
+	_, ok := InteractionContext(withEnvelope(context.Background(), readEnvelope([]byte(`{"type":2}`))))
+	assert.False(t, ok)
+	assert.Nil(t, IntegrationOwners(context.Background()))
+}
+
+func TestInteractionContext_Async(t *testing.T) {
+	server, _ := recordingServer(t)
+	q := &fakeSQS{}
+	var contexts []InteractionContextType
+	var owners []map[ApplicationIntegrationType]string
+	e, calls := commandEndpoint(t,
+		WithDiscordEndpoint(server.URL),
+		WithDeferToSQS("queue_url", q),
+		WithMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				c, _ := InteractionContext(ctx)
+				contexts = append(contexts, c)
+				owners = append(owners, IntegrationOwners(ctx))
+				return next(ctx, s, i)
+			}
+		}),
+	)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}), &body))
+	body["context"] = 2
+	body["authorizing_integration_owners"] = map[string]string{"1": "user_id"}
+
+	// given an interaction which was deferred to the queue
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, body)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.Len(t, q.bodies, 1)
+
+	// when it is handled from SQS, SNS and Kinesis
+	_, err = e.HandleSQS(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: q.bodies[0]}}})
+	require.NoError(t, err)
+	require.NoError(t, e.HandleSNS(context.Background(), &events.SNSEvent{Records: []events.SNSEventRecord{
+		{SNS: events.SNSEntity{MessageID: "1", Message: q.bodies[0]}},
+	}}))
+	_, err = e.HandleKinesis(context.Background(), &events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{SequenceNumber: "1", Data: []byte(q.bodies[0])}},
+	}})
+	require.NoError(t, err)
+
+	// then the installation context should be available to each handler
+	assert.Equal(t, 3, *calls)
+	want := map[ApplicationIntegrationType]string{ApplicationIntegrationUserInstall: "user_id"}
+	assert.Equal(t, []InteractionContextType{InteractionContextPrivateChannel, InteractionContextPrivateChannel, InteractionContextPrivateChannel}, contexts)
+	assert.Equal(t, []map[ApplicationIntegrationType]string{want, want, want}, owners)
+}
@@ -0,0 +1,35 @@
+// Package paramstore gets parameters from the AWS Parameters and Secrets Lambda Extension.
+package paramstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/elliotwms/bot-lambda/tracing"
+	"github.com/winebarrel/secretlamb"
+)
+
+// ErrEmpty is returned when the parameter has no value.
+var ErrEmpty = errors.New("parameter empty")
+
+// Get gets the decrypted value of the parameter from the Parameters and Secrets Lambda Extension, using the client if it
+// is not nil.
+func Get(ctx context.Context, name string, client *http.Client) (string, error) {
+	parameters := secretlamb.MustNewParameters()
+	if client != nil {
+		parameters.HTTPClient = client
+	}
+	parameters.HTTPClient = tracing.Client(ctx, parameters.HTTPClient)
+
+	p, err := parameters.GetWithContext(ctx, name, secretlamb.ParameterWithDecryption())
+	if err != nil {
+		return "", err
+	}
+
+	if p == nil || p.Parameter.Value == "" {
+		return "", ErrEmpty
+	}
+
+	return p.Parameter.Value, nil
+}
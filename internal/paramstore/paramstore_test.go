@@ -0,0 +1,46 @@
+package paramstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/winebarrel/secretlamb"
+)
+
+// extension serves the parameter with the value from a fake Parameters and Secrets Lambda Extension
+func extension(t *testing.T, value string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "true", r.URL.Query().Get("withDecryption"))
+
+		bs, _ := json.Marshal(secretlamb.ParameterOutput{Parameter: secretlamb.ParameterOutputParameter{Name: r.URL.Query().Get("name"), Value: value}})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bs)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", u.Port())
+}
+
+func TestGet(t *testing.T) {
+	extension(t, "bar")
+
+	v, err := Get(context.Background(), "foo", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+}
+
+func TestGet_Empty(t *testing.T) {
+	extension(t, "")
+
+	_, err := Get(context.Background(), "foo", nil)
+
+	require.ErrorIs(t, err, ErrEmpty)
+}
@@ -0,0 +1,88 @@
+package bot_lambda
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultLocale is the locale used when an interaction does not specify one
+const defaultLocale = discordgo.EnglishUS
+
+// Localizer translates the message identified by key into the locale, e.g. using a message catalogue.
+type Localizer interface {
+	Localize(locale discordgo.Locale, key string, args ...any) string
+}
+
+// LocalizerFunc adapts a function to a Localizer.
+type LocalizerFunc func(locale discordgo.Locale, key string, args ...any) string
+
+// Localize calls f(locale, key, args...).
+func (f LocalizerFunc) Localize(locale discordgo.Locale, key string, args ...any) string {
+	return f(locale, key, args...)
+}
+
+// WithLocalizer sets the Localizer used by Localize to translate messages into the locale of the interaction.
+func WithLocalizer(l Localizer) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.localizer = l
+	}
+}
+
+// WithDefaultLocale overrides the locale used for interactions which do not specify one. Defaults to
+// discordgo.EnglishUS.
+func WithDefaultLocale(locale discordgo.Locale) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.defaultLocale = locale
+	}
+}
+
+type localeKey struct{}
+
+type localization struct {
+	locale    discordgo.Locale
+	localizer Localizer
+}
+
+// withLocale adds the resolved locale of the interaction and the endpoint's localizer to the context
+func (e *Endpoint) withLocale(ctx context.Context, i *discordgo.InteractionCreate) context.Context {
+	return context.WithValue(ctx, localeKey{}, localization{
+		locale:    resolveLocale(i, e.defaultLocale),
+		localizer: e.localizer,
+	})
+}
+
+// resolveLocale returns the locale of the user who invoked the interaction, falling back to the guild's locale and then
+// the default
+func resolveLocale(i *discordgo.InteractionCreate, fallback discordgo.Locale) discordgo.Locale {
+	switch {
+	case i.Locale != "":
+		return i.Locale
+	case i.GuildLocale != nil && *i.GuildLocale != "":
+		return *i.GuildLocale
+	default:
+		return fallback
+	}
+}
+
+// LocaleFromContext returns the locale of the interaction being handled: the user's locale, falling back to the guild's
+// locale and then the endpoint's default locale (see WithDefaultLocale).
+func LocaleFromContext(ctx context.Context) discordgo.Locale {
+	l, ok := ctx.Value(localeKey{}).(localization)
+	if !ok {
+		return defaultLocale
+	}
+
+	return l.locale
+}
+
+// Localize translates the message identified by key into the locale of the interaction being handled using the
+// endpoint's Localizer (see WithLocalizer). The key is returned if no Localizer is configured.
+func Localize(ctx context.Context, key string, args ...any) string {
+	l, ok := ctx.Value(localeKey{}).(localization)
+	if !ok || l.localizer == nil {
+		return key
+	}
+
+	return l.localizer.Localize(l.locale, key, args...)
+}
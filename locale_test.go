@@ -0,0 +1,74 @@
+package bot_lambda
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleFromContext(t *testing.T) {
+	french := discordgo.French
+	empty := discordgo.Locale("")
+
+	tests := []struct {
+		name        string
+		options     []Option
+		locale      discordgo.Locale
+		guildLocale *discordgo.Locale
+		want        discordgo.Locale
+	}{
+		{name: "user locale", locale: discordgo.German, guildLocale: &french, want: discordgo.German},
+		{name: "guild locale", guildLocale: &french, want: discordgo.French},
+		{name: "empty guild locale", guildLocale: &empty, want: discordgo.EnglishUS},
+		{name: "default", want: discordgo.EnglishUS},
+		{name: "configured default", options: []Option{WithDefaultLocale(discordgo.EnglishGB)}, want: discordgo.EnglishGB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got discordgo.Locale
+			e, _ := commandEndpoint(t, append(tt.options, WithMiddleware(func(next InteractionHandler) InteractionHandler {
+				return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+					got = LocaleFromContext(ctx)
+					return next(ctx, s, i)
+				}
+			}))...)
+
+			i := fooCommand()
+			i.Locale = tt.locale
+			i.GuildLocale = tt.guildLocale
+			send(t, e, i)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	var got string
+	e, _ := commandEndpoint(t,
+		WithLocalizer(LocalizerFunc(func(locale discordgo.Locale, key string, args ...any) string {
+			return fmt.Sprintf("%s:%s:%v", string(locale), key, args)
+		})),
+		WithMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				got = Localize(ctx, "greeting", "bob")
+				return next(ctx, s, i)
+			}
+		}),
+	)
+
+	i := fooCommand()
+	i.Locale = discordgo.French
+	send(t, e, i)
+
+	assert.Equal(t, "fr:greeting:[bob]", got)
+}
+
+func TestLocalize_NoLocalizer(t *testing.T) {
+	assert.Equal(t, "greeting", Localize(context.Background(), "greeting"))
+	assert.Equal(t, discordgo.EnglishUS, LocaleFromContext(context.Background()))
+}
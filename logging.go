@@ -0,0 +1,69 @@
+package bot_lambda
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// WithInteractionLogging logs a single structured record for each interaction at the level, including the command
+// name, guild, channel, user ID and locale. User-provided content such as option values is never logged.
+func WithInteractionLogging(level slog.Level) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.interactionLogLevel = &level
+	}
+}
+
+// logInteraction logs the interaction's metadata if interaction logging is enabled
+func (e *Endpoint) logInteraction(ctx context.Context, i *discordgo.InteractionCreate) {
+	if e.interactionLogLevel == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("interaction_id", i.ID),
+		slog.Int("interaction_type", int(i.Type)),
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		attrs = append(attrs, slog.String("command", i.ApplicationCommandData().Name))
+	}
+
+	if i.GuildID != "" {
+		attrs = append(attrs, slog.String("guild_id", i.GuildID))
+	}
+	if i.ChannelID != "" {
+		attrs = append(attrs, slog.String("channel_id", i.ChannelID))
+	}
+
+	switch {
+	case i.Member != nil && i.Member.User != nil:
+		attrs = append(attrs, slog.String("user_id", i.Member.User.ID))
+	case i.User != nil:
+		attrs = append(attrs, slog.String("user_id", i.User.ID))
+	}
+
+	if i.Locale != "" {
+		attrs = append(attrs, slog.String("locale", string(i.Locale)))
+	}
+
+	e.logger(ctx).LogAttrs(ctx, *e.interactionLogLevel, "Interaction received", attrs...)
+}
+
+type loggerKey struct{}
+
+// logger returns the request-scoped logger from the context, falling back to the endpoint's logger
+func (e *Endpoint) logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return e.log
+}
+
+// withLogAttrs adds the attributes to the request-scoped logger in the context
+func (e *Endpoint) withLogAttrs(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, e.logger(ctx).With(args...))
+}
@@ -0,0 +1,146 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes the JSON log records written to the buffer
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]any
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+
+	return records
+}
+
+func findRecord(records []map[string]any, msg string) map[string]any {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func TestWithInteractionLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	e := New(nil, WithLogger(l), WithInteractionLogging(slog.LevelInfo))
+
+	send(t, e, &discordgo.Interaction{
+		ID:        "interaction_id",
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   "guild_id",
+		ChannelID: "channel_id",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: "user_id", Username: "username"}},
+		Locale:    discordgo.EnglishGB,
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        "foo",
+			CommandType: discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "secret", Type: discordgo.ApplicationCommandOptionString, Value: "user content"},
+			},
+		},
+	})
+
+	out := buf.String()
+	r := findRecord(logRecords(t, buf), "Interaction received")
+	require.NotNil(t, r)
+	assert.Equal(t, "INFO", r["level"])
+	assert.Equal(t, "interaction_id", r["interaction_id"])
+	assert.Equal(t, "foo", r["command"])
+	assert.Equal(t, "guild_id", r["guild_id"])
+	assert.Equal(t, "channel_id", r["channel_id"])
+	assert.Equal(t, "user_id", r["user_id"])
+	assert.Equal(t, "en-GB", r["locale"])
+	assert.NotContains(t, out, "user content")
+	assert.NotContains(t, out, "username")
+}
+
+func TestWithInteractionLogging_Disabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	e := New(nil, WithLogger(l))
+
+	send(t, e, fooCommand())
+
+	assert.Nil(t, findRecord(logRecords(t, buf), "Interaction received"))
+}
+
+func TestWithLogger_Router(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return errors.New("oops")
+	})
+
+	send(t, e, fooCommand())
+
+	// then the router's error should be logged with the endpoint's logger
+	r := findRecord(logRecords(t, buf), "Failed to handle interaction")
+	require.NotNil(t, r)
+	assert.Equal(t, "oops", r["error"])
+}
+
+func TestBuild_OptionOrdering(t *testing.T) {
+	failing := func(calls *int) router.ApplicationCommandHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+			*calls++
+			return errors.New("oops")
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint
+	}{
+		{name: "logger then command", setup: func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint {
+			return New(nil, WithLogger(l)).WithChatApplicationCommand("foo", h)
+		}},
+		{name: "command then logger", setup: func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint {
+			e := New(nil).WithChatApplicationCommand("foo", h)
+			WithLogger(l)(e)
+			return e
+		}},
+		{name: "router then logger", setup: func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint {
+			return New(nil, WithRouter(router.New(router.WithLogger(l))), WithLogger(l)).WithChatApplicationCommand("foo", h)
+		}},
+		{name: "command then router", setup: func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint {
+			e := New(nil, WithLogger(l)).WithChatApplicationCommand("foo", h)
+			WithRouter(router.New(router.WithLogger(l)))(e)
+			return e
+		}},
+		{name: "built then command", setup: func(l *slog.Logger, h router.ApplicationCommandHandler) *Endpoint {
+			return New(nil, WithLogger(l)).Build().WithChatApplicationCommand("foo", h)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			calls := 0
+			e := tt.setup(slog.New(slog.NewJSONHandler(buf, nil)), failing(&calls))
+
+			send(t, e, fooCommand())
+
+			// then the command should be handled by a router using the configured logger
+			assert.Equal(t, 1, calls)
+			assert.NotNil(t, findRecord(logRecords(t, buf), "Failed to handle interaction"))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package bot_lambda
+
+// MetricDeferredResponseFailed is counted when Discord rejects a deferred response. See WithMetrics.
+const MetricDeferredResponseFailed = "deferred_response_failed"
+
+// Metrics counts notable events which occur within the endpoint.
+type Metrics interface {
+	Count(name string, value int64)
+}
+
+// WithMetrics configures the endpoint to count notable events using the metrics implementation.
+func WithMetrics(m Metrics) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.metrics = m
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Count(string, int64) {}
@@ -0,0 +1,60 @@
+package bot_lambda
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// InteractionHandler handles an interaction, returning an optional synchronous response.
+type InteractionHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error)
+
+// Middleware wraps an InteractionHandler, allowing behaviour to be added before or after the interaction is routed.
+// Middleware may short-circuit the chain by returning without calling next.
+type Middleware func(next InteractionHandler) InteractionHandler
+
+type scopedMiddleware struct {
+	types []discordgo.InteractionType
+	mw    Middleware
+}
+
+// WithMiddleware adds middleware which is run for every interaction. Middleware is run in the order it is added.
+func WithMiddleware(mw ...Middleware) Option {
+	return WithMiddlewareForTypes(nil, mw...)
+}
+
+// WithMiddlewareForTypes adds middleware which is only run for the given interaction types.
+func WithMiddlewareForTypes(types []discordgo.InteractionType, mw ...Middleware) Option {
+	return func(endpoint *Endpoint) {
+		for _, m := range mw {
+			endpoint.middleware = append(endpoint.middleware, scopedMiddleware{types: types, mw: m})
+		}
+	}
+}
+
+// WithPreSessionMiddleware adds middleware which is run for every interaction before the session is resolved from the
+// session provider, so that it can short-circuit the chain without the cost of resolving the session. The session
+// passed to pre-session middleware is scoped to the interaction's token. Pre-session middleware is run before any
+// middleware added with WithMiddleware.
+func WithPreSessionMiddleware(mw ...Middleware) Option {
+	return func(endpoint *Endpoint) {
+		for _, m := range mw {
+			endpoint.preSessionMiddleware = append(endpoint.preSessionMiddleware, scopedMiddleware{mw: m})
+		}
+	}
+}
+
+// chain wraps the handler with the middleware which applies to the interaction type
+func chain(middleware []scopedMiddleware, t discordgo.InteractionType, h InteractionHandler) InteractionHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		m := middleware[i]
+		if m.types != nil && !slices.Contains(m.types, t) {
+			continue
+		}
+
+		h = m.mw(h)
+	}
+
+	return h
+}
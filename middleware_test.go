@@ -0,0 +1,120 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+)
+
+func counting(calls *[]string, name string) Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			*calls = append(*calls, name)
+			return next(ctx, s, i)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	e := New(nil, WithLogger(slogt.New(t)), WithMiddleware(counting(&calls, "a"), counting(&calls, "b")))
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, []string{"a", "b"}, calls)
+}
+
+func TestMiddleware_ShortCircuit(t *testing.T) {
+	e, calls := commandEndpoint(t, WithMiddleware(responding(&discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "short circuit"},
+	})))
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddlewareForTypes(t *testing.T) {
+	var calls []string
+	e := New(
+		nil,
+		WithLogger(slogt.New(t)),
+		WithMiddlewareForTypes([]discordgo.InteractionType{discordgo.InteractionApplicationCommand}, counting(&calls, "command")),
+		WithMiddleware(counting(&calls, "all")),
+	)
+
+	// when an autocomplete interaction is received then only the unscoped middleware runs
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommandAutocomplete,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo"},
+	})
+	assert.Equal(t, []string{"all"}, calls)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// when a component interaction is received then only the unscoped middleware runs
+	calls = nil
+	send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionMessageComponent,
+		Data: discordgo.MessageComponentInteractionData{CustomID: "foo"},
+	})
+	assert.Equal(t, []string{"all"}, calls)
+
+	// when a command interaction is received then both middleware run
+	calls = nil
+	send(t, e, &discordgo.Interaction{
+		Type:  discordgo.InteractionApplicationCommand,
+		Token: "interaction_token",
+		Data:  discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+	assert.Equal(t, []string{"command", "all"}, calls)
+}
+
+func TestWithPreSessionMiddleware(t *testing.T) {
+	var calls []string
+	var provided int
+	e, handled := commandEndpoint(t,
+		WithPreSessionMiddleware(counting(&calls, "pre-session"), responding(&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "short circuit"},
+		})),
+		WithMiddleware(counting(&calls, "middleware")),
+	)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		provided++
+		return &discordgo.Session{}, nil
+	})
+
+	// when pre-session middleware short-circuits the chain
+	res := send(t, e, fooCommand())
+
+	// then the session should never be resolved
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"pre-session"}, calls)
+	assert.Equal(t, 0, provided)
+	assert.Equal(t, 0, *handled)
+}
+
+func TestWithPreSessionMiddleware_Order(t *testing.T) {
+	var calls []string
+	e, handled := commandEndpoint(t,
+		WithMiddleware(counting(&calls, "middleware")),
+		WithPreSessionMiddleware(counting(&calls, "pre-session")),
+	)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		calls = append(calls, "provider")
+		return &discordgo.Session{}, nil
+	})
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, []string{"pre-session", "provider", "middleware"}, calls)
+	assert.Equal(t, 1, *handled)
+}
@@ -0,0 +1,16 @@
+package bot_lambda
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// MetricPing is counted when the endpoint responds to a PING. See WithMetrics.
+const MetricPing = "ping"
+
+// pong responds to a PING, which Discord sends to verify the endpoint. This bypasses the middleware chain and session
+// resolution, so the response is as fast as possible.
+func (e *Endpoint) pong() *discordgo.InteractionResponse {
+	e.metrics.Count(MetricPing, 1)
+
+	return &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong}
+}
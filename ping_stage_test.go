@@ -19,14 +19,20 @@ import (
 )
 
 type PingStage struct {
-	t           *testing.T
-	require     *require.Assertions
-	handler     func(context.Context, *events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLResponse, error)
-	res         *events.LambdaFunctionURLResponse
-	assert      *assert.Assertions
-	privateKey  ed25519.PrivateKey
-	omitHeaders bool
-	httpMethod  string
+	t              *testing.T
+	require        *require.Assertions
+	res            *events.LambdaFunctionURLResponse
+	assert         *assert.Assertions
+	publicKey      ed25519.PublicKey
+	privateKey     ed25519.PrivateKey
+	options        []Option
+	omitHeaders    bool
+	httpMethod     string
+	combinedHeader string
+	headerValue    string
+	headers        map[string]string
+	calls          int
+	counts         map[string]int64
 }
 
 func NewPingStage(t *testing.T) (*PingStage, *PingStage, *PingStage) {
@@ -39,8 +45,9 @@ func NewPingStage(t *testing.T) (*PingStage, *PingStage, *PingStage) {
 		t:          t,
 		assert:     assert.New(t),
 		require:    require.New(t),
-		handler:    New(publicKey, WithLogger(slogt.New(t))).HandleRequest,
+		publicKey:  publicKey,
 		privateKey: privateKey,
+		options:    []Option{WithLogger(slogt.New(t))},
 		httpMethod: http.MethodPost,
 	}
 
@@ -69,16 +76,37 @@ func (s *PingStage) a_ping_is_sent() *PingStage {
 		Body: string(bs),
 	}
 
-	if !s.omitHeaders {
+	switch {
+	case s.omitHeaders:
+	case s.combinedHeader != "":
+		v := s.headerValue
+		if v == "" {
+			v = "Ed25519 " + hex.EncodeToString(sign) + "." + ts
+		}
+		req.Headers = map[string]string{s.combinedHeader: v}
+	default:
 		req.Headers = map[string]string{
 			"X-Signature-Ed25519":   hex.EncodeToString(sign),
 			"X-Signature-Timestamp": ts,
 		}
 	}
 
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
+	}
+	for k, v := range s.headers {
+		req.Headers[k] = v
+	}
+
 	ctx, _ := xray.BeginSegment(context.Background(), "test")
 
-	s.res, err = s.handler(ctx, req)
+	e := New(s.publicKey, s.options...).
+		WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+			s.calls++
+			return &discordgo.Session{}, nil
+		})
+
+	s.res, err = e.HandleRequest(ctx, req)
 	s.require.NoError(err)
 
 	return s
@@ -107,10 +135,68 @@ func (s *PingStage) an_invalid_signature() {
 	s.privateKey = k
 }
 
-func (s *PingStage) request_will_omit_signature_headers() {
+func (s *PingStage) request_will_omit_signature_headers() *PingStage {
 	s.omitHeaders = true
+
+	return s
 }
 
 func (s *PingStage) request_will_have_method(method string) {
 	s.httpMethod = method
 }
+
+func (s *PingStage) the_endpoint_reads_the_combined_signature_header(name string) *PingStage {
+	s.options = append(s.options, WithCombinedSignatureHeader(name, ParseAuthorizationSignature))
+	s.combinedHeader = name
+
+	return s
+}
+
+func (s *PingStage) the_combined_signature_header_has_value(v string) {
+	s.headerValue = v
+}
+
+func (s *PingStage) the_endpoint_has_options(options ...Option) *PingStage {
+	s.options = append(s.options, options...)
+
+	return s
+}
+
+func (s *PingStage) request_will_have_header(k, v string) *PingStage {
+	if s.headers == nil {
+		s.headers = map[string]string{}
+	}
+	s.headers[k] = v
+
+	return s
+}
+
+func (s *PingStage) the_endpoint_has_middleware_and_metrics() *PingStage {
+	s.counts = map[string]int64{}
+
+	return s.the_endpoint_has_options(
+		WithMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, session *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				s.calls++
+				return next(ctx, session, i)
+			}
+		}),
+		WithMetrics(countingMetrics(s.counts)),
+	)
+}
+
+func (s *PingStage) the_session_provider_and_middleware_should_not_have_been_called() *PingStage {
+	s.assert.Zero(s.calls)
+
+	return s
+}
+
+func (s *PingStage) the_metric_should_have_been_counted(name string, n int64) {
+	s.assert.Equal(n, s.counts[name])
+}
+
+type countingMetrics map[string]int64
+
+func (m countingMetrics) Count(name string, value int64) {
+	m[name] += value
+}
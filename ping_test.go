@@ -54,3 +54,112 @@ func TestPing_InvalidMethod(t *testing.T) {
 	then.
 		the_status_code_should_be(http.StatusMethodNotAllowed)
 }
+
+func TestPing_CombinedSignatureHeader(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_reads_the_combined_signature_header("Authorization")
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusOK).and().
+		a_pong_should_be_received()
+}
+
+func TestPing_CombinedSignatureHeader_Malformed(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_reads_the_combined_signature_header("Authorization").and().
+		the_combined_signature_header_has_value("Ed25519 malformed")
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusUnauthorized)
+}
+
+func TestPing_CombinedSignatureHeader_Missing(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_reads_the_combined_signature_header("Authorization").and().
+		request_will_omit_signature_headers()
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusUnauthorized)
+}
+
+func TestPing_PreFilter(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_has_options(WithPreFilter(func(headers map[string]string, body []byte) (bool, int) {
+			if headers["user-agent"] == "scanner" {
+				return false, http.StatusForbidden
+			}
+
+			return true, 0
+		})).and().
+		request_will_have_header("user-agent", "scanner")
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusForbidden)
+}
+
+func TestPing_PreFilter_NoStatus(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_has_options(WithPreFilter(func(headers map[string]string, body []byte) (bool, int) {
+			return false, 0
+		}))
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusForbidden)
+}
+
+func TestPing_PreFilter_Allowed(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_has_options(WithPreFilter(func(headers map[string]string, body []byte) (bool, int) {
+			return headers["user-agent"] != "scanner", http.StatusForbidden
+		})).and().
+		request_will_have_header("user-agent", "Discord-Interactions/1.0")
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusOK).and().
+		a_pong_should_be_received()
+}
+
+func TestPing_FastPath(t *testing.T) {
+	given, when, then := NewPingStage(t)
+
+	given.
+		the_endpoint_has_middleware_and_metrics()
+
+	when.
+		a_ping_is_sent()
+
+	then.
+		the_status_code_should_be(http.StatusOK).and().
+		the_session_provider_and_middleware_should_not_have_been_called().and().
+		the_metric_should_have_been_counted(MetricPing, 1)
+}
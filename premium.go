@@ -0,0 +1,59 @@
+package bot_lambda
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// InteractionResponsePremiumRequired responds to an interaction with an upgrade button, for apps with monetization
+// enabled. It is not yet defined by discordgo.
+// See https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-response-object-interaction-callback-type
+const InteractionResponsePremiumRequired discordgo.InteractionResponseType = 10
+
+// PremiumRequiredResponse builds a response which prompts the user to upgrade, e.g. when the interaction's entitlements
+// do not include the SKU required by a command. See HasEntitlement.
+func PremiumRequiredResponse() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{Type: InteractionResponsePremiumRequired}
+}
+
+// Entitlement is an entitlement of the user or guild which invoked an interaction, granting access to a premium SKU.
+// It is not yet defined by discordgo.
+// See https://discord.com/developers/docs/resources/entitlement#entitlement-object
+type Entitlement struct {
+	ID            string `json:"id"`
+	SKUID         string `json:"sku_id"`
+	ApplicationID string `json:"application_id"`
+	UserID        string `json:"user_id,omitempty"`
+	GuildID       string `json:"guild_id,omitempty"`
+	Type          int    `json:"type"`
+	Deleted       bool   `json:"deleted"`
+	Consumed      bool   `json:"consumed,omitempty"`
+}
+
+type entitlementsKey struct{}
+
+// EntitlementsFromContext returns the entitlements sent with the interaction being handled.
+func EntitlementsFromContext(ctx context.Context) []Entitlement {
+	entitlements, _ := ctx.Value(entitlementsKey{}).([]Entitlement)
+
+	return entitlements
+}
+
+// HasEntitlement returns true if the interaction being handled has an active entitlement to the SKU.
+func HasEntitlement(ctx context.Context, skuID string) bool {
+	return slices.ContainsFunc(EntitlementsFromContext(ctx), func(e Entitlement) bool {
+		return e.SKUID == skuID && !e.Deleted
+	})
+}
+
+// withEntitlements adds the entitlements read from the interaction body to the context, as discordgo.Interaction does
+// not decode them
+func withEntitlements(ctx context.Context, entitlements []Entitlement) context.Context {
+	if len(entitlements) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, entitlementsKey{}, entitlements)
+}
@@ -0,0 +1,57 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requirePremium responds with premium required unless the interaction has an entitlement to the SKU
+func requirePremium(skuID string) Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			if !HasEntitlement(ctx, skuID) {
+				return PremiumRequiredResponse(), nil
+			}
+
+			return next(ctx, s, i)
+		}
+	}
+}
+
+func TestPremiumRequiredResponse(t *testing.T) {
+	e, calls := commandEndpoint(t, WithMiddleware(requirePremium("premium")))
+
+	request := func(entitlements []Entitlement) *events.LambdaFunctionURLResponse {
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}), &body))
+		body["entitlements"] = entitlements
+
+		res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+			},
+			Body: string(mustMarshal(t, body)),
+		})
+		require.NoError(t, err)
+
+		return res
+	}
+
+	// when the interaction has no entitlement then premium is required
+	res := request([]Entitlement{{SKUID: "premium", Deleted: true}, {SKUID: "other"}})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"type": 10}`, res.Body)
+	assert.Equal(t, 0, *calls)
+
+	// when the interaction has an entitlement then it is handled
+	res = request([]Entitlement{{SKUID: "premium"}})
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+}
@@ -0,0 +1,53 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/elliotwms/bot-lambda/internal/paramstore"
+	"github.com/elliotwms/bot-lambda/tracing"
+)
+
+// NewFromHex creates a new Endpoint using the hex-encoded public key provided by the Discord developer portal.
+func NewFromHex(publicKeyHex string, options ...Option) (*Endpoint, error) {
+	publicKey, err := ParsePublicKey(publicKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(publicKey, options...), nil
+}
+
+// ParsePublicKey decodes a hex-encoded ed25519 public key, ensuring it is the correct size.
+func ParsePublicKey(publicKeyHex string) (ed25519.PublicKey, error) {
+	bs, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+
+	if len(bs) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: expected %d bytes, got %d", ed25519.PublicKeySize, len(bs))
+	}
+
+	return bs, nil
+}
+
+// PublicKeyFromParamStore retrieves the hex-encoded public key stored in param store.
+// It is intended to be called once during initialisation, keeping the public key alongside the bot token.
+func PublicKeyFromParamStore(ctx context.Context, paramName string) (k ed25519.PublicKey, err error) {
+	ctx, seg := tracing.StartSpan(ctx, "public key param store")
+	defer func() { seg.End(err) }()
+	if paramName == "" {
+		return nil, errors.New("empty public key paramstore parameter name")
+	}
+
+	value, err := paramstore.Get(ctx, paramName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePublicKey(value)
+}
@@ -0,0 +1,88 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/stretchr/testify/require"
+	"github.com/winebarrel/secretlamb"
+)
+
+type PublicKeyStage struct {
+	t         *testing.T
+	require   *require.Assertions
+	publicKey ed25519.PublicKey
+	err       error
+}
+
+func NewPublicKeyStage(t *testing.T) (*PublicKeyStage, *PublicKeyStage, *PublicKeyStage) {
+	s := &PublicKeyStage{
+		t:       t,
+		require: require.New(t),
+	}
+
+	return s, s, s
+}
+
+func (s *PublicKeyStage) and() *PublicKeyStage {
+	return s
+}
+
+func (s *PublicKeyStage) a_parameter_named_x_with_value_y(x, y string) *PublicKeyStage {
+	return s.param_store_will_return(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := json.Marshal(secretlamb.ParameterOutput{
+			Parameter: secretlamb.ParameterOutputParameter{
+				Name:  x,
+				Value: y,
+			},
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bs)
+	})
+}
+
+func (s *PublicKeyStage) param_store_will_return(f http.HandlerFunc) *PublicKeyStage {
+	server := httptest.NewServer(f)
+	s.t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	s.require.NoError(err)
+
+	s.t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", u.Port())
+
+	return s
+}
+
+func (s *PublicKeyStage) the_public_key_is_requested_from_param_store_with_param_named(name string) *PublicKeyStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	s.publicKey, s.err = PublicKeyFromParamStore(ctx, name)
+
+	return s
+}
+
+func (s *PublicKeyStage) no_error_should_be_returned() *PublicKeyStage {
+	s.require.NoError(s.err)
+
+	return s
+}
+
+func (s *PublicKeyStage) the_public_key_should_be(k ed25519.PublicKey) {
+	s.require.Equal(k, s.publicKey)
+}
+
+func (s *PublicKeyStage) an_error_should_be_returned(err string) {
+	s.require.ErrorContains(s.err, err)
+}
+
+func (s *PublicKeyStage) the_param_store_server_is_unavailable() *PublicKeyStage {
+	return s.param_store_will_return(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+}
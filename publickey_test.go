@@ -0,0 +1,100 @@
+package bot_lambda
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromHex(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	e, err := NewFromHex(hex.EncodeToString(publicKey))
+
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, e.publicKey)
+}
+
+func TestNewFromHex_InvalidHex(t *testing.T) {
+	e, err := NewFromHex("not hex")
+
+	assert.Nil(t, e)
+	assert.ErrorContains(t, err, "decode public key")
+}
+
+func TestNewFromHex_WrongLength(t *testing.T) {
+	e, err := NewFromHex("abcdef")
+
+	assert.Nil(t, e)
+	assert.ErrorContains(t, err, "invalid public key size: expected 32 bytes, got 3")
+}
+
+func TestPublicKeyFromParamStore(t *testing.T) {
+	given, when, then := NewPublicKeyStage(t)
+
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", hex.EncodeToString(publicKey))
+
+	when.
+		the_public_key_is_requested_from_param_store_with_param_named("foo")
+
+	then.
+		no_error_should_be_returned().and().
+		the_public_key_should_be(publicKey)
+}
+
+func TestPublicKeyFromParamStore_EmptyParamName(t *testing.T) {
+	_, when, then := NewPublicKeyStage(t)
+
+	when.
+		the_public_key_is_requested_from_param_store_with_param_named("")
+
+	then.
+		an_error_should_be_returned("empty public key paramstore parameter name")
+}
+
+func TestPublicKeyFromParamStore_HttpError(t *testing.T) {
+	given, when, then := NewPublicKeyStage(t)
+
+	given.
+		the_param_store_server_is_unavailable()
+
+	when.
+		the_public_key_is_requested_from_param_store_with_param_named("foo")
+
+	then.
+		an_error_should_be_returned("failed to get parameter - http request error")
+}
+
+func TestPublicKeyFromParamStore_EmptyParamValue(t *testing.T) {
+	given, when, then := NewPublicKeyStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "")
+
+	when.
+		the_public_key_is_requested_from_param_store_with_param_named("foo")
+
+	then.
+		an_error_should_be_returned("parameter empty")
+}
+
+func TestPublicKeyFromParamStore_InvalidKey(t *testing.T) {
+	given, when, then := NewPublicKeyStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "abcdef")
+
+	when.
+		the_public_key_is_requested_from_param_store_with_param_named("foo")
+
+	then.
+		an_error_should_be_returned("invalid public key size")
+}
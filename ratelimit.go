@@ -0,0 +1,150 @@
+package bot_lambda
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// RateLimiter decides whether an interaction with the key is allowed to be handled.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) bool
+}
+
+// RateLimitKey returns the key an interaction is rate limited by. See RateLimitByUser and RateLimitByGuild.
+type RateLimitKey func(i *discordgo.InteractionCreate) string
+
+// RateLimitByUser rate limits interactions by the user who invoked them.
+func RateLimitByUser(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+
+	return ""
+}
+
+// RateLimitByGuild rate limits interactions by the guild they were invoked in. Interactions outside a guild are
+// rate limited by user.
+func RateLimitByGuild(i *discordgo.InteractionCreate) string {
+	if i.GuildID != "" {
+		return i.GuildID
+	}
+
+	return RateLimitByUser(i)
+}
+
+// WithRateLimiter rate limits interactions by the key, protecting downstream systems from bursts of interactions.
+// Interactions which are denied are not handled, and are instead responded to with an ephemeral message asking the
+// user to slow down (or no choices, for autocomplete interactions). The rate limiter is added to the pre-session
+// middleware chain in the order it is configured (see WithPreSessionMiddleware), so that denied interactions do not
+// resolve the session.
+func WithRateLimiter(limiter RateLimiter, key RateLimitKey) Option {
+	return func(endpoint *Endpoint) {
+		WithPreSessionMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				if limiter.Allow(ctx, key(i)) {
+					return next(ctx, s, i)
+				}
+
+				endpoint.logger(ctx).Warn("Interaction rate limited", "interaction_id", i.ID)
+
+				return rateLimitedResponse(i), nil
+			}
+		})(endpoint)
+	}
+}
+
+// rateLimitedResponse returns the response to an interaction which was rate limited
+func rateLimitedResponse(i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return AutocompleteResponse()
+	}
+
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "You're doing that too often, please slow down.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}
+}
+
+// TokenBucket is an in-memory RateLimiter which allows bursts of up to capacity interactions per key, refilling one
+// token per interval. State is held per Lambda container, so it only limits interactions handled by warm containers.
+// Buckets which have refilled to capacity are indistinguishable from new buckets, so they are periodically evicted to
+// bound the memory used by keys which are no longer active.
+type TokenBucket struct {
+	capacity float64
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket with the capacity, refilling one token per interval.
+func NewTokenBucket(capacity int, interval time.Duration) *TokenBucket {
+	return &TokenBucket{
+		capacity: float64(capacity),
+		interval: interval,
+		now:      time.Now,
+		buckets:  map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the key's bucket, returning false if the bucket is empty.
+func (l *TokenBucket) Allow(_ context.Context, key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.capacity, b.tokens+float64(now.Sub(b.last))/float64(l.interval))
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// prune evicts full buckets, at most once per the time taken for an empty bucket to refill
+func (l *TokenBucket) prune(now time.Time) {
+	if l.lastPrune.IsZero() {
+		l.lastPrune = now
+	}
+
+	refill := time.Duration(l.capacity * float64(l.interval))
+	if now.Sub(l.lastPrune) < refill {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if b.tokens+float64(now.Sub(b.last))/float64(l.interval) >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastPrune = now
+}
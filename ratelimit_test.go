@@ -0,0 +1,118 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimiter(t *testing.T) {
+	e, calls := commandEndpoint(t, WithRateLimiter(NewTokenBucket(1, time.Hour), RateLimitByUser))
+
+	command := func(userID string) *discordgo.Interaction {
+		i := fooCommand()
+		i.Member = &discordgo.Member{User: &discordgo.User{ID: userID}}
+		return i
+	}
+
+	// when the first interaction is received then it is allowed
+	res := send(t, e, command("1"))
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+
+	// when the user invokes another interaction then it is rate limited
+	res = send(t, e, command("1"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, discordgo.InteractionResponseChannelMessageWithSource, v.Type)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, v.Data.Flags)
+
+	// when another user invokes an interaction then it is allowed
+	res = send(t, e, command("2"))
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestWithRateLimiter_PreSession(t *testing.T) {
+	var provided int
+	e, calls := commandEndpoint(t, WithRateLimiter(NewTokenBucket(1, time.Hour), RateLimitByUser))
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		provided++
+		return &discordgo.Session{}, nil
+	})
+
+	i := fooCommand()
+	i.User = &discordgo.User{ID: "1"}
+	send(t, e, i)
+
+	// when an interaction is rate limited
+	res := send(t, e, i)
+
+	// then the session should not be resolved
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, provided)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestTokenBucket(t *testing.T) {
+	current := time.Now()
+	l := NewTokenBucket(2, time.Second)
+	l.now = func() time.Time { return current }
+	ctx := context.Background()
+
+	// the bucket allows bursts up to its capacity
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.False(t, l.Allow(ctx, "foo"))
+	assert.True(t, l.Allow(ctx, "bar"))
+
+	// tokens are refilled each interval
+	current = current.Add(time.Second)
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.False(t, l.Allow(ctx, "foo"))
+
+	// the bucket does not refill beyond its capacity
+	current = current.Add(time.Hour)
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.False(t, l.Allow(ctx, "foo"))
+}
+
+func TestTokenBucket_Prune(t *testing.T) {
+	current := time.Now()
+	l := NewTokenBucket(2, time.Second)
+	l.now = func() time.Time { return current }
+	ctx := context.Background()
+
+	// given buckets for keys which are no longer active and a key which is still limited
+	assert.True(t, l.Allow(ctx, "foo"))
+	assert.True(t, l.Allow(ctx, "bar"))
+	current = current.Add(time.Second)
+	assert.True(t, l.Allow(ctx, "baz"))
+	assert.True(t, l.Allow(ctx, "baz"))
+
+	// when the buckets have had time to refill
+	current = current.Add(time.Second)
+	assert.True(t, l.Allow(ctx, "qux"))
+
+	// then the full buckets should be evicted, keeping the bucket which is still refilling
+	assert.Len(t, l.buckets, 2)
+	assert.Contains(t, l.buckets, "baz")
+	assert.Contains(t, l.buckets, "qux")
+	assert.True(t, l.Allow(ctx, "baz"))
+	assert.False(t, l.Allow(ctx, "baz"))
+}
+
+func TestRateLimitByGuild(t *testing.T) {
+	assert.Equal(t, "guild", RateLimitByGuild(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{GuildID: "guild"}}))
+	assert.Equal(t, "user", RateLimitByGuild(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{User: &discordgo.User{ID: "user"}}}))
+}
@@ -0,0 +1,118 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultReplayWindow is the time for which signatures are remembered when no maximum timestamp age is configured
+const defaultReplayWindow = 5 * time.Minute
+
+// now returns the current time, and is overridden in tests
+var now = time.Now
+
+// NonceStore records the signatures of requests which have been handled, to detect replayed requests. Implementations
+// shared between execution environments (e.g. backed by DynamoDB or ElastiCache) protect against replays to other
+// instances of the function.
+type NonceStore interface {
+	// Seen records the key for the ttl, returning true if it has already been recorded and has not expired.
+	Seen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// WithMaxTimestampAge rejects requests with a signature timestamp more than d in the past, or more than d in the
+// future to tolerate clock skew, limiting the window in which a captured request can be replayed.
+func WithMaxTimestampAge(d time.Duration) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.maxTimestampAge = d
+	}
+}
+
+// WithReplayProtection rejects requests with a signature which has already been seen, protecting against replays
+// within the freshness window (see WithMaxTimestampAge). Signatures are remembered for the maximum timestamp age, or 5
+// minutes if it is not configured. Requests are rejected if the store returns an error.
+func WithReplayProtection(store NonceStore) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.nonces = store
+	}
+}
+
+// checkTimestamp rejects the request if its signature timestamp is outside the maximum timestamp age
+func (e *Endpoint) checkTimestamp(ts string) error {
+	if e.maxTimestampAge <= 0 {
+		return nil
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return verificationError(ReasonStaleTimestamp, fmt.Errorf("invalid timestamp: %w", err))
+	}
+
+	if age := now().Sub(time.Unix(unix, 0)).Abs(); age > e.maxTimestampAge {
+		return verificationError(ReasonStaleTimestamp, fmt.Errorf("timestamp outside maximum age: %s", age))
+	}
+
+	return nil
+}
+
+// checkReplay rejects the request if its signature has already been seen. The key is built from the decoded signature,
+// as the same signature can be encoded differently, e.g. in upper case hex.
+func (e *Endpoint) checkReplay(ctx context.Context, ts string, sig []byte) error {
+	if e.nonces == nil {
+		return nil
+	}
+
+	ttl := e.maxTimestampAge
+	if ttl <= 0 {
+		ttl = defaultReplayWindow
+	}
+
+	seen, err := e.nonces.Seen(ctx, ts+":"+hex.EncodeToString(sig), ttl)
+	if err != nil {
+		return fmt.Errorf("check replay: %w", err)
+	}
+	if seen {
+		return verificationError(ReasonReplayed, errors.New("replayed request"))
+	}
+
+	return nil
+}
+
+// MemoryNonceStore is a NonceStore which remembers signatures in memory. It only protects against replays to the same
+// execution environment.
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// NewMemoryNonceStore creates a new MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{expires: map[string]time.Time{}}
+}
+
+// Seen records the key for the ttl, returning true if it has already been recorded and has not expired.
+func (s *MemoryNonceStore) Seen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+
+	// evict expired keys so the store doesn't grow unbounded in a long-lived environment
+	for k, expires := range s.expires {
+		if !t.Before(expires) {
+			delete(s.expires, k)
+		}
+	}
+
+	if _, ok := s.expires[key]; ok {
+		return true, nil
+	}
+
+	s.expires[key] = t.Add(ttl)
+
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package bot_lambda
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/bottest"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendSigned sends the interaction to the endpoint as a function URL request signed at the timestamp
+func sendSigned(t *testing.T, e *Endpoint, privateKey ed25519.PrivateKey, ts time.Time, i *discordgo.Interaction) *events.LambdaFunctionURLResponse {
+	body := mustMarshal(t, &discordgo.InteractionCreate{Interaction: i})
+
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Headers: bottest.SignRequest(privateKey, strconv.FormatInt(ts.Unix(), 10), body),
+		Body:    string(body),
+	})
+	require.NoError(t, err)
+
+	return res
+}
+
+func TestWithReplayProtection(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	e := New(publicKey, WithLogger(slogt.New(t)), WithMaxTimestampAge(time.Minute), WithReplayProtection(NewMemoryNonceStore()))
+	ts := time.Now()
+
+	// when a signed request is received
+	res := sendSigned(t, e, privateKey, ts, &discordgo.Interaction{Type: discordgo.InteractionPing})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// then an identical replayed request should be rejected
+	res = sendSigned(t, e, privateKey, ts, &discordgo.Interaction{Type: discordgo.InteractionPing})
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// and a new request should be accepted
+	res = sendSigned(t, e, privateKey, ts.Add(time.Second), &discordgo.Interaction{Type: discordgo.InteractionPing})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestWithReplayProtection_ReencodedSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	e := New(publicKey, WithLogger(slogt.New(t)), WithReplayProtection(NewMemoryNonceStore()))
+	body := mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})
+	headers := bottest.SignRequest(privateKey, strconv.FormatInt(time.Now().Unix(), 10), body)
+
+	request := func(headers map[string]string) *events.LambdaFunctionURLResponse {
+		res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+			},
+			Headers: headers,
+			Body:    string(body),
+		})
+		require.NoError(t, err)
+
+		return res
+	}
+
+	res := request(headers)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// when the request is replayed with the signature re-encoded in upper case
+	headers[bottest.HeaderSignature] = strings.ToUpper(headers[bottest.HeaderSignature])
+	res = request(headers)
+
+	// then it should still be rejected as a replay
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestWithMaxTimestampAge(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		age  time.Duration
+		code int
+	}{
+		{name: "fresh", age: 0, code: http.StatusOK},
+		{name: "within skew", age: -30 * time.Second, code: http.StatusOK},
+		{name: "stale", age: 2 * time.Minute, code: http.StatusUnauthorized},
+		{name: "future", age: -2 * time.Minute, code: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(publicKey, WithLogger(slogt.New(t)), WithMaxTimestampAge(time.Minute))
+
+			res := sendSigned(t, e, privateKey, time.Now().Add(-tt.age), &discordgo.Interaction{Type: discordgo.InteractionPing})
+
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	s := NewMemoryNonceStore()
+
+	seen, _ := s.Seen(context.Background(), "foo", time.Minute)
+	assert.False(t, seen)
+
+	seen, _ = s.Seen(context.Background(), "foo", time.Minute)
+	assert.True(t, seen)
+
+	// after the ttl the key is forgotten
+	current = current.Add(time.Minute)
+	seen, _ = s.Seen(context.Background(), "foo", time.Minute)
+	assert.False(t, seen)
+}
@@ -0,0 +1,34 @@
+package bot_lambda
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the AWS request ID of the invocation being handled, which is included in the endpoint's
+// logs as aws_request_id to correlate them with the function's CloudWatch logs.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+
+	return id, ok
+}
+
+// withRequestID adds the invocation's AWS request ID to the context and logger, falling back to the request ID in the
+// event's request context if the Lambda context is not available
+func (e *Endpoint) withRequestID(ctx context.Context, fallback string) context.Context {
+	id := fallback
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+		id = lc.AwsRequestID
+	}
+
+	if id == "" {
+		return ctx
+	}
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	return e.withLogAttrs(ctx, "aws_request_id", id)
+}
@@ -0,0 +1,64 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		fallback string
+		want     string
+	}{
+		{name: "lambda context", ctx: lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda_request_id"}), fallback: "event_request_id", want: "lambda_request_id"},
+		{name: "event request context", ctx: context.Background(), fallback: "event_request_id", want: "event_request_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			var got string
+			e := New(nil,
+				WithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+				WithMiddleware(func(next InteractionHandler) InteractionHandler {
+					return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+						got, _ = RequestIDFromContext(ctx)
+						return next(ctx, s, i)
+					}
+				}),
+			)
+
+			_, err := e.HandleRequest(tt.ctx, &events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					RequestID: tt.fallback,
+					HTTP:      events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+				},
+				Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+			})
+			require.NoError(t, err)
+
+			// then the request ID should be available to handlers
+			assert.Equal(t, tt.want, got)
+
+			// and included in the endpoint's logs
+			records := logRecords(t, buf)
+			require.NotEmpty(t, records)
+			for _, r := range []string{"Received request", "Handling interaction"} {
+				record := findRecord(records, r)
+				require.NotNil(t, record, r)
+				assert.Equal(t, tt.want, record["aws_request_id"], r)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ErrAlreadyResponded is returned when a second initial response is attempted for an interaction, e.g. a handler
+// returning a message for an interaction which has already been deferred or responded to with a modal. Discord only
+// accepts one initial response per interaction, so follow-up messages should be used instead.
+var ErrAlreadyResponded = errors.New("interaction has already been responded to")
+
+type responseGuardKey struct{}
+
+// withResponseGuard adds a guard to the context which tracks whether the interaction has been responded to
+func withResponseGuard(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseGuardKey{}, new(atomic.Bool))
+}
+
+// markResponded records that the interaction has been responded to, returning false if it already had been
+func markResponded(ctx context.Context) bool {
+	guard, ok := ctx.Value(responseGuardKey{}).(*atomic.Bool)
+	if !ok {
+		return true
+	}
+
+	return guard.CompareAndSwap(false, true)
+}
+
+// Respond sends the initial response to the interaction being handled using the session, e.g. to open a modal from a
+// command handler, which cannot return a response. ErrAlreadyResponded is returned if the interaction has already
+// been responded to by the endpoint or another call to Respond.
+func Respond(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, res *discordgo.InteractionResponse) error {
+	if !markResponded(ctx) {
+		return ErrAlreadyResponded
+	}
+
+	return s.InteractionRespond(i.Interaction, res, discordgo.WithContext(ctx))
+}
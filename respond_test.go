@@ -0,0 +1,94 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respondWith sends the initial response with Respond, returning the result of a second response
+func respondWith(res *discordgo.InteractionResponse, second *error) Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			if err := Respond(ctx, s, i, res); err != nil {
+				return nil, err
+			}
+
+			*second = Respond(ctx, s, i, res)
+
+			return next(ctx, s, i)
+		}
+	}
+}
+
+func modalResponse() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{CustomID: "modal", Title: "Modal"},
+	}
+}
+
+func TestRespond_Twice(t *testing.T) {
+	server, requests := recordingServer(t)
+
+	var second error
+	e, calls := commandEndpoint(t, WithDiscordEndpoint(server.URL), WithMiddleware(respondWith(modalResponse(), &second)))
+
+	res := send(t, e, fooCommand())
+
+	// then only the first response should be sent
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 1, *calls)
+	assert.Len(t, requests(), 1)
+	assert.ErrorIs(t, second, ErrAlreadyResponded)
+}
+
+func TestRespond_ThenReturnResponse(t *testing.T) {
+	server, requests := recordingServer(t)
+
+	var second error
+	e, _ := commandEndpoint(t,
+		WithDiscordEndpoint(server.URL),
+		WithMiddleware(respondWith(modalResponse(), &second), slowly(0, "message")),
+	)
+
+	// when the handler opens a modal and returns a message
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+
+	// then the message should not be sent
+	assert.ErrorIs(t, err, ErrAlreadyResponded)
+	assert.Len(t, requests(), 1)
+}
+
+func TestRespond_AfterDeferredResponse(t *testing.T) {
+	server, requests := recordingServer(t)
+
+	var respondErr error
+	e, _ := commandEndpoint(t,
+		WithDiscordEndpoint(server.URL),
+		WithDeferredResponseEnabled(true),
+		WithMiddleware(func(next InteractionHandler) InteractionHandler {
+			return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+				respondErr = Respond(ctx, s, i, modalResponse())
+				return next(ctx, s, i)
+			}
+		}),
+	)
+
+	res := send(t, e, fooCommand())
+
+	// then the modal should not be sent after the deferred response
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.ErrorIs(t, respondErr, ErrAlreadyResponded)
+	assert.Len(t, requests(), 1)
+}
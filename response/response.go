@@ -0,0 +1,106 @@
+// Package response provides a fluent builder for interaction responses, e.g.
+//
+//	response.Message("hi").Ephemeral().Embed(e).Build()
+package response
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Builder builds a discordgo.InteractionResponse.
+type Builder struct {
+	res *discordgo.InteractionResponse
+}
+
+func newBuilder(t discordgo.InteractionResponseType) *Builder {
+	return &Builder{res: &discordgo.InteractionResponse{
+		Type: t,
+		Data: &discordgo.InteractionResponseData{},
+	}}
+}
+
+// Message builds a response which sends a message with the content.
+func Message(content string) *Builder {
+	return newBuilder(discordgo.InteractionResponseChannelMessageWithSource).Content(content)
+}
+
+// Deferred builds a response which acknowledges the interaction, showing a loading state until a follow-up is sent.
+func Deferred() *Builder {
+	return newBuilder(discordgo.InteractionResponseDeferredChannelMessageWithSource)
+}
+
+// Update builds a response to a component interaction which updates the component's message with the content.
+func Update(content string) *Builder {
+	return newBuilder(discordgo.InteractionResponseUpdateMessage).Content(content)
+}
+
+// DeferredUpdate builds a response to a component interaction which acknowledges it without updating the message.
+func DeferredUpdate() *Builder {
+	return &Builder{res: &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}}
+}
+
+// Modal builds a response which opens a modal with the components.
+func Modal(customID, title string, components ...discordgo.MessageComponent) *Builder {
+	b := newBuilder(discordgo.InteractionResponseModal)
+	b.res.Data.CustomID = customID
+	b.res.Data.Title = title
+
+	return b.Components(components...)
+}
+
+// Autocomplete builds a response to an autocomplete interaction with the choices.
+func Autocomplete(choices ...*discordgo.ApplicationCommandOptionChoice) *Builder {
+	b := newBuilder(discordgo.InteractionApplicationCommandAutocompleteResult)
+	b.res.Data.Choices = choices
+
+	return b
+}
+
+// Content sets the content of the message.
+func (b *Builder) Content(content string) *Builder {
+	b.data().Content = content
+
+	return b
+}
+
+// Ephemeral makes the message only visible to the user who invoked the interaction.
+func (b *Builder) Ephemeral() *Builder {
+	b.data().Flags |= discordgo.MessageFlagsEphemeral
+
+	return b
+}
+
+// Embed adds the embeds to the message.
+func (b *Builder) Embed(embeds ...*discordgo.MessageEmbed) *Builder {
+	b.data().Embeds = append(b.data().Embeds, embeds...)
+
+	return b
+}
+
+// Components adds the components to the message or modal.
+func (b *Builder) Components(components ...discordgo.MessageComponent) *Builder {
+	b.data().Components = append(b.data().Components, components...)
+
+	return b
+}
+
+// AllowedMentions sets the mentions which are allowed to notify users.
+func (b *Builder) AllowedMentions(m *discordgo.MessageAllowedMentions) *Builder {
+	b.data().AllowedMentions = m
+
+	return b
+}
+
+// Build returns the response.
+func (b *Builder) Build() *discordgo.InteractionResponse {
+	return b.res
+}
+
+// data returns the response data, creating it if the response type does not have data by default
+func (b *Builder) data() *discordgo.InteractionResponseData {
+	if b.res.Data == nil {
+		b.res.Data = &discordgo.InteractionResponseData{}
+	}
+
+	return b.res.Data
+}
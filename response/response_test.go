@@ -0,0 +1,91 @@
+package response
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	embed := &discordgo.MessageEmbed{Title: "embed"}
+	input := discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.TextInput{CustomID: "input", Label: "Input", Style: discordgo.TextInputShort},
+	}}
+
+	tests := []struct {
+		name string
+		got  *discordgo.InteractionResponse
+		want *discordgo.InteractionResponse
+	}{
+		{
+			name: "message",
+			got:  Message("hi").Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Content: "hi"},
+			},
+		},
+		{
+			name: "ephemeral message with embed",
+			got:  Message("hi").Ephemeral().Embed(embed).Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "hi",
+					Flags:   discordgo.MessageFlagsEphemeral,
+					Embeds:  []*discordgo.MessageEmbed{embed},
+				},
+			},
+		},
+		{
+			name: "deferred",
+			got:  Deferred().Ephemeral().Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+			},
+		},
+		{
+			name: "deferred update",
+			got:  DeferredUpdate().Build(),
+			want: &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate},
+		},
+		{
+			name: "update",
+			got:  Update("updated").Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseUpdateMessage,
+				Data: &discordgo.InteractionResponseData{Content: "updated"},
+			},
+		},
+		{
+			name: "modal",
+			got:  Modal("modal", "Title", input).Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseModal,
+				Data: &discordgo.InteractionResponseData{
+					CustomID:   "modal",
+					Title:      "Title",
+					Components: []discordgo.MessageComponent{input},
+				},
+			},
+		},
+		{
+			name: "autocomplete",
+			got:  Autocomplete(&discordgo.ApplicationCommandOptionChoice{Name: "foo", Value: "foo"}).Build(),
+			want: &discordgo.InteractionResponse{
+				Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+				Data: &discordgo.InteractionResponseData{
+					Choices: []*discordgo.ApplicationCommandOptionChoice{{Name: "foo", Value: "foo"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.got)
+		})
+	}
+}
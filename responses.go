@@ -0,0 +1,33 @@
+package bot_lambda
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// AutocompleteResponse builds a response to an autocomplete interaction with the choices.
+func AutocompleteResponse(choices ...*discordgo.ApplicationCommandOptionChoice) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}
+}
+
+// LocalizedChoice builds an autocomplete choice with names localised for each locale.
+func LocalizedChoice(name string, value interface{}, localizations map[discordgo.Locale]string) *discordgo.ApplicationCommandOptionChoice {
+	return &discordgo.ApplicationCommandOptionChoice{
+		Name:              name,
+		NameLocalizations: localizations,
+		Value:             value,
+	}
+}
+
+// AckResponse builds a response which acknowledges a component interaction without updating the message.
+func AckResponse() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+}
+
+// UpdateMessageResponse builds a response to a component interaction which updates the component's message with the
+// data, e.g. to disable a button once it has been clicked.
+func UpdateMessageResponse(data *discordgo.InteractionResponseData) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage, Data: data}
+}
@@ -0,0 +1,55 @@
+package bot_lambda
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutocompleteResponse_LocalizedChoices(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithMiddleware(responding(AutocompleteResponse(
+		LocalizedChoice("Red", "red", map[discordgo.Locale]string{discordgo.French: "Rouge"}),
+		LocalizedChoice("Blue", "blue", nil),
+	))))
+
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommandAutocomplete,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "colour"},
+	})
+
+	var v struct {
+		Type discordgo.InteractionResponseType `json:"type"`
+		Data struct {
+			Choices []struct {
+				Name              string            `json:"name"`
+				NameLocalizations map[string]string `json:"name_localizations"`
+				Value             string            `json:"value"`
+			} `json:"choices"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+
+	assert.Equal(t, discordgo.InteractionApplicationCommandAutocompleteResult, v.Type)
+	require.Len(t, v.Data.Choices, 2)
+	assert.Equal(t, "Red", v.Data.Choices[0].Name)
+	assert.Equal(t, map[string]string{"fr": "Rouge"}, v.Data.Choices[0].NameLocalizations)
+	assert.Equal(t, "red", v.Data.Choices[0].Value)
+	assert.Nil(t, v.Data.Choices[1].NameLocalizations)
+}
+
+func TestAckResponse(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithMiddleware(responding(AckResponse())))
+
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionMessageComponent,
+		Data: discordgo.MessageComponentInteractionData{CustomID: "foo"},
+	})
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"type":6}`, res.Body)
+}
@@ -0,0 +1,42 @@
+package bot_lambda
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+)
+
+type handlerRetry struct {
+	attempts    int
+	isRetryable func(error) bool
+}
+
+// WithHandlerRetry re-invokes application command handlers registered with the endpoint up to attempts times whilst
+// they return an error for which isRetryable returns true, stopping early if the context is done.
+// Handlers are invoked again from the start so must be idempotent: any side effects (such as sending messages) which
+// happen before the error is returned will be repeated.
+func WithHandlerRetry(attempts int, isRetryable func(error) bool) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.handlerRetry = &handlerRetry{attempts: attempts, isRetryable: isRetryable}
+	}
+}
+
+// withRetry wraps the handler, applying the endpoint's handler retry configuration if present
+func (e *Endpoint) withRetry(h router.ApplicationCommandHandler) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		if e.handlerRetry == nil {
+			return h(ctx, s, i, data)
+		}
+
+		for attempt := 1; ; attempt++ {
+			err = h(ctx, s, i, data)
+			if err == nil || attempt >= e.handlerRetry.attempts || !e.handlerRetry.isRetryable(err) || ctx.Err() != nil {
+				return err
+			}
+
+			e.logger(ctx).Warn("Retrying handler", slog.Int("attempt", attempt), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func fooCommand() *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	}
+}
+
+func TestHandlerRetry(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithHandlerRetry(3, isTransient))
+
+	calls := 0
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+
+		return nil
+	})
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHandlerRetry_MaxAttempts(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithHandlerRetry(3, isTransient))
+
+	calls := 0
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls++
+		return errTransient
+	})
+
+	send(t, e, fooCommand())
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestHandlerRetry_NotRetryable(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithHandlerRetry(3, isTransient))
+
+	calls := 0
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		calls++
+		return errors.New("permanent")
+	})
+
+	send(t, e, fooCommand())
+
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,136 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/sessionprovider"
+)
+
+// WithDiscordEndpoint overrides the base URL of the Discord API used by the interaction-scoped session, e.g. to target
+// fakediscord in tests. The base URL may include a path prefix, with or without a trailing slash. URLs which cannot be
+// parsed are logged and the default endpoint is used instead.
+func WithDiscordEndpoint(baseURL string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.discordBaseURL = baseURL
+	}
+}
+
+// parseDiscordEndpoint parses the base URL of the Discord API
+func parseDiscordEndpoint(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// normalise the base URL so that the request paths can be appended whether or not it has a trailing slash
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u, nil
+}
+
+// WithUserAgent sets the user agent of requests made by the interaction-scoped session, which Discord expects to
+// identify the bot in the form "DiscordBot ($url, $version)". Sessions from a session provider are not modified, as they
+// may be shared; wrap the provider with sessionprovider.WithUserAgent instead.
+// See https://discord.com/developers/docs/reference#user-agent
+func WithUserAgent(userAgent string) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.userAgent = userAgent
+	}
+}
+
+// interactionSession builds a session scoped to the interaction using the interaction's token. Sessions share the
+// endpoint's session client, so connections are reused between interactions handled by a warm container.
+func (e *Endpoint) interactionSession(i *discordgo.InteractionCreate) *discordgo.Session {
+	s, _ := discordgo.New("Bot " + i.Token)
+	s.Client = e.sessionClient
+	if e.userAgent != "" {
+		s.UserAgent = e.userAgent
+	}
+
+	return s
+}
+
+// newSessionClient builds the HTTP client shared by interaction-scoped sessions
+func (e *Endpoint) newSessionClient() *http.Client {
+	// match the timeout of the default discordgo client
+	c := &http.Client{Timeout: 20 * time.Second}
+	if e.httpClient != nil {
+		c = e.httpClient
+	}
+
+	if e.dryRun != nil {
+		e.dryRun.log = e.log
+		c = &http.Client{Timeout: c.Timeout, Transport: e.dryRun}
+	}
+
+	if e.discordEndpoint != nil {
+		rewritten := *c
+		rewritten.Transport = &endpointTransport{base: e.discordEndpoint, next: c.Transport}
+		c = &rewritten
+	}
+
+	return e.tracer.Client(c)
+}
+
+// endpointTransport rewrites requests to target the base URL
+type endpointTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.base.Scheme
+	r.URL.Host = t.base.Host
+	r.URL.Path = t.base.Path + r.URL.Path
+	r.URL.RawPath = ""
+	r.Host = ""
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return next.RoundTrip(r)
+}
+
+// sessionError wraps an error returned when resolving the session from the session provider
+type sessionError struct {
+	err error
+}
+
+func (e *sessionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sessionError) Unwrap() error {
+	return e.err
+}
+
+// isSessionError returns true if the error was returned when resolving the session from the session provider
+func isSessionError(err error) bool {
+	var serr *sessionError
+	return errors.As(err, &serr)
+}
+
+// sessionFailed logs the session provider's failure, returning the status to respond with. Permanent errors (see
+// sessionprovider.Permanent), such as a misconfigured parameter name, will not succeed if retried and respond with a
+// 500, while other errors are assumed to be transient, e.g. the Parameters and Secrets Lambda Extension being
+// unavailable, and respond with a 503.
+func (e *Endpoint) sessionFailed(ctx context.Context, err error) int {
+	if sessionprovider.IsPermanent(err) {
+		e.logger(ctx).Error("Session provider failed permanently, check its configuration", "error", err, "permanent", true)
+		return http.StatusInternalServerError
+	}
+
+	e.logger(ctx).Error("Session provider unavailable", "error", err, "permanent", false)
+
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,230 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/sessionprovider"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDiscordEndpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		prefix string
+	}{
+		{name: "host", path: ""},
+		{name: "trailing slash", path: "/"},
+		{name: "repeated trailing slash", path: "//"},
+		{name: "path prefix", path: "/discord", prefix: "/discord"},
+		{name: "path prefix with trailing slash", path: "/discord/", prefix: "/discord"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the interaction response endpoint on the custom host expects a request
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+			}))
+			t.Cleanup(server.Close)
+
+			// given an endpoint targeting the custom host
+			e := New(
+				nil,
+				WithLogger(slogt.New(t)),
+				WithDeferredResponseEnabled(true),
+				WithDiscordEndpoint(server.URL+tt.path),
+			)
+			e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+				return nil
+			})
+
+			// when the endpoint receives the interaction
+			send(t, e, &discordgo.Interaction{
+				ID:    "interaction_id",
+				Type:  discordgo.InteractionApplicationCommand,
+				Token: "interaction_token",
+				Data:  discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+			})
+
+			// then the deferred response should have been sent to the custom host
+			assert.Equal(t, []string{tt.prefix + "/api/v9/interactions/interaction_id/interaction_token/callback"}, paths)
+		})
+	}
+}
+
+func TestWithDiscordEndpoint_Invalid(t *testing.T) {
+	// given an endpoint with a discord endpoint which cannot be parsed
+	buf := &bytes.Buffer{}
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithDiscordEndpoint("http://[::1"))
+
+	// then the error should be logged and the default endpoint used
+	assert.NotNil(t, findRecord(logRecords(t, buf), "Invalid discord endpoint, using the default endpoint"))
+	assert.Nil(t, e.discordEndpoint)
+}
+
+func TestWithSessionProvider_NilSession(t *testing.T) {
+	e, calls := commandEndpoint(t)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, nil
+	})
+
+	res := send(t, e, fooCommand())
+
+	// then the contract violation should be treated as a permanent failure
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestWithSessionProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     int
+		failures int
+	}{
+		{name: "transient", err: errors.New("unavailable"), code: http.StatusServiceUnavailable, failures: 1},
+		{name: "permanent", err: sessionprovider.Permanent(errors.New("misconfigured")), code: http.StatusInternalServerError, failures: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, calls := commandEndpoint(t)
+			e.WithSessionProvider(sessionprovider.NewMock(nil, tt.err).Provider())
+
+			// when the interaction is received over HTTP then the status reflects whether it can be retried
+			res := send(t, e, fooCommand())
+			assert.Equal(t, tt.code, res.StatusCode)
+
+			// and when it is received from SQS then only transient failures are reported for retry
+			sqs, err := e.HandleSQS(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{
+				{MessageId: "message_id", Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()}))},
+			}})
+			require.NoError(t, err)
+			assert.Len(t, sqs.BatchItemFailures, tt.failures)
+
+			assert.Equal(t, 0, *calls)
+		})
+	}
+}
+
+func TestWithSessionProvider_Selector(t *testing.T) {
+	var got *discordgo.Session
+	e, calls := commandEndpoint(t, WithMiddleware(func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			got = s
+			return next(ctx, s, i)
+		}
+	}))
+	e.WithSessionProvider(sessionprovider.Selector(sessionprovider.ApplicationID, map[string]sessionprovider.Provider{
+		"1234": sessionprovider.Static(&discordgo.Session{Token: "Bot foo"}),
+	}))
+
+	i := fooCommand()
+	i.AppID = "1234"
+	send(t, e, i)
+
+	require.NotNil(t, got)
+	assert.Equal(t, "Bot foo", got.Token)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestSessionOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		option Option
+	}{
+		{name: "session", option: WithSessionOption(&discordgo.Session{Token: "Bot foo"})},
+		{name: "provider", option: WithSessionProviderOption(sessionprovider.Static(&discordgo.Session{Token: "Bot foo"}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given an endpoint configured entirely through options
+			var got *discordgo.Session
+			e := New(nil,
+				WithLogger(slogt.New(t)),
+				tt.option,
+				WithMiddleware(func(next InteractionHandler) InteractionHandler {
+					return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+						got = s
+						return next(ctx, s, i)
+					}
+				}),
+			).WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+				return nil
+			})
+
+			send(t, e, fooCommand())
+
+			// then the configured session should be used
+			require.NotNil(t, got)
+			assert.Equal(t, "Bot foo", got.Token)
+		})
+	}
+}
+
+func TestInteractionSession_SharedClient(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	e, calls := commandEndpoint(t, WithDiscordEndpoint(server.URL), WithDeferredResponseEnabled(true))
+
+	// when several interactions are handled
+	for range 3 {
+		send(t, e, fooCommand())
+	}
+
+	// then the sessions should share a client, reusing the connection
+	assert.Equal(t, 3, *calls)
+	assert.Same(t, e.interactionSession(fooCreate()).Client, e.interactionSession(fooCreate()).Client)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, conns)
+}
+
+func fooCreate() *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: fooCommand()}
+}
+
+func TestWithUserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+	}))
+	t.Cleanup(server.Close)
+
+	e, _ := commandEndpoint(t,
+		WithDiscordEndpoint(server.URL),
+		WithDeferredResponseEnabled(true),
+		WithUserAgent("DiscordBot (https://example.com, 1.0)"),
+	)
+
+	send(t, e, fooCommand())
+
+	// then the deferred response should be sent with the configured user agent
+	assert.Equal(t, []string{"DiscordBot (https://example.com, 1.0)"}, userAgents)
+}
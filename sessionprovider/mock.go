@@ -0,0 +1,58 @@
+package sessionprovider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Mock is a configurable Provider for tests, which returns the session or error it was last configured with and counts
+// the calls made to it. Unlike Static, it can simulate a provider which fails, e.g.
+//
+//	mock := sessionprovider.NewMock(nil, errors.New("unavailable"))
+//	endpoint.WithSessionProvider(mock.Provider())
+//
+// A Mock is safe for concurrent use.
+type Mock struct {
+	mu      sync.Mutex
+	session *discordgo.Session
+	err     error
+	calls   int
+}
+
+// NewMock creates a Mock which returns the session and error
+func NewMock(s *discordgo.Session, err error) *Mock {
+	return &Mock{session: s, err: err}
+}
+
+// Return configures the session and error returned by subsequent calls
+func (m *Mock) Return(s *discordgo.Session, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.session, m.err = s, err
+}
+
+// Calls returns the number of times the Provider has been called
+func (m *Mock) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls
+}
+
+// Provider returns the Provider backed by the Mock
+func (m *Mock) Provider() Provider {
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.calls++
+		if m.err != nil {
+			return nil, m.err
+		}
+
+		return m.session, nil
+	}
+}
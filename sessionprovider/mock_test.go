@@ -0,0 +1,39 @@
+package sessionprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	s := &discordgo.Session{Token: "Bot foo"}
+	mock := NewMock(s, nil)
+
+	v, err := mock.Provider()(context.Background())
+	require.NoError(t, err)
+	require.Same(t, s, v)
+
+	// when an error is injected then it is returned
+	mock.Return(nil, errors.New("unavailable"))
+
+	v, err = mock.Provider()(context.Background())
+	require.EqualError(t, err, "unavailable")
+	require.Nil(t, v)
+
+	require.Equal(t, 2, mock.Calls())
+}
+
+func TestMock_WithRetry(t *testing.T) {
+	mock := NewMock(nil, Permanent(errors.New("misconfigured")))
+
+	_, err := WithRetry(mock.Provider(), 3, time.Millisecond)(context.Background())
+
+	// then permanent errors should not be retried
+	require.True(t, IsPermanent(err))
+	require.Equal(t, 1, mock.Calls())
+}
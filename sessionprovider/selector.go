@@ -0,0 +1,50 @@
+package sessionprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type interactionKey struct{}
+
+// WithInteraction adds the interaction being handled to the context passed to the Provider. The endpoint does this
+// before resolving a session.
+func WithInteraction(ctx context.Context, i *discordgo.InteractionCreate) context.Context {
+	return context.WithValue(ctx, interactionKey{}, i)
+}
+
+// InteractionFromContext returns the interaction a session is being provided for.
+func InteractionFromContext(ctx context.Context) (*discordgo.InteractionCreate, bool) {
+	i, ok := ctx.Value(interactionKey{}).(*discordgo.InteractionCreate)
+
+	return i, ok && i != nil
+}
+
+// Selector chooses the Provider for each interaction by the key returned by the select func, e.g. to use a different
+// bot token per application ID or shard. Interactions with a key which has no Provider result in a permanent error.
+// As a session can only be selected for an interaction, Selector returns an error when called without one, such as
+// from Endpoint.Check.
+func Selector(selectKey func(ctx context.Context, i *discordgo.InteractionCreate) string, providers map[string]Provider) Provider {
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		i, ok := InteractionFromContext(ctx)
+		if !ok {
+			return nil, errors.New("no interaction to select provider")
+		}
+
+		key := selectKey(ctx, i)
+		f, ok := providers[key]
+		if !ok {
+			return nil, Permanent(fmt.Errorf("no provider for key %q", key))
+		}
+
+		return f(ctx)
+	}
+}
+
+// ApplicationID selects the provider for the interaction by its application ID. See Selector.
+func ApplicationID(_ context.Context, i *discordgo.InteractionCreate) string {
+	return i.AppID
+}
@@ -0,0 +1,39 @@
+package sessionprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector(t *testing.T) {
+	foo := &discordgo.Session{Token: "Bot foo"}
+	bar := &discordgo.Session{Token: "Bot bar"}
+	f := Selector(ApplicationID, map[string]Provider{
+		"1": Static(foo),
+		"2": Static(bar),
+	})
+
+	interaction := func(appID string) context.Context {
+		return WithInteraction(context.Background(), &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{AppID: appID}})
+	}
+
+	s, err := f(interaction("1"))
+	require.NoError(t, err)
+	require.Same(t, foo, s)
+
+	s, err = f(interaction("2"))
+	require.NoError(t, err)
+	require.Same(t, bar, s)
+
+	// an unknown key is a permanent error
+	_, err = f(interaction("3"))
+	require.ErrorContains(t, err, `no provider for key "3"`)
+	require.True(t, IsPermanent(err))
+
+	// a session cannot be selected without an interaction
+	_, err = f(context.Background())
+	require.Error(t, err)
+}
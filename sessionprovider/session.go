@@ -4,41 +4,191 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/bwmarrin/discordgo"
-	"github.com/winebarrel/secretlamb"
+	"github.com/elliotwms/bot-lambda/internal/paramstore"
+	"github.com/elliotwms/bot-lambda/tracing"
 )
 
+var errNilSession = errors.New("provider returned nil session without error")
+
+// Provider provides a session for handling an interaction. Providers must return either a non-nil session or an error.
 type Provider func(ctx context.Context) (*discordgo.Session, error)
 
+// PermanentError wraps an error returned by a Provider which will not succeed if retried, such as a misconfiguration.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks the error as permanent. See PermanentError.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent returns true if the error is a PermanentError.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
 // ParamStore initialises the Discord Session using the token stored in param store
 func ParamStore(paramName string) Provider {
+	return ParamStoreWithClient(paramName, nil)
+}
+
+// ParamStoreWithClient initialises the Discord Session using the token stored in param store, using the provided client
+// for both the param store and Discord requests. If the client is nil then the default client is used.
+func ParamStoreWithClient(paramName string, client *http.Client) Provider {
 	return func(ctx context.Context) (s *discordgo.Session, err error) {
-		ctx, seg := xray.BeginSubsegment(ctx, "param store")
-		defer seg.Close(err)
-		if paramName == "" {
-			return nil, errors.New("empty discord token paramstore parameter name")
+		ctx, seg := tracing.StartSpan(ctx, "param store")
+		defer func() { seg.End(err) }()
+
+		token, err := getParameter(ctx, paramName, client)
+		if err != nil {
+			return nil, err
 		}
 
-		parameters := secretlamb.MustNewParameters()
-		parameters.HTTPClient = xray.Client(parameters.HTTPClient)
+		return paramStoreSession(ctx, token, client), nil
+	}
+}
+
+// DefaultExtensionTTL is the default time for which the Parameters and Secrets Lambda Extension caches parameters.
+// See https://docs.aws.amazon.com/systems-manager/latest/userguide/ps-integration-lambda-extensions.html
+const DefaultExtensionTTL = 300 * time.Second
 
-		p, err := parameters.GetWithContext(ctx, paramName, secretlamb.ParameterWithDecryption())
+type cachedParameter struct {
+	value   string
+	expires time.Time
+}
+
+// parameterFetch is an in-flight fetch of a parameter, which is shared by concurrent callers
+type parameterFetch struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// parameterCache holds the decrypted parameters fetched by ParamStoreCached, keyed by parameter name, and the fetches
+// which are in flight
+var parameterCache = struct {
+	sync.Mutex
+	values  map[string]cachedParameter
+	fetches map[string]*parameterFetch
+}{values: map[string]cachedParameter{}, fetches: map[string]*parameterFetch{}}
+
+// ParamStoreCached initialises the Discord Session using the token stored in param store, caching the decrypted token
+// in-process for the ttl to avoid a round-trip to the Parameters and Secrets Lambda Extension for each interaction. The
+// cache is shared by all providers for the parameter, and concurrent calls for an expired parameter share one fetch. The
+// ttl should not exceed the extension's, so that rotated tokens are picked up; if it is zero then DefaultExtensionTTL is
+// used.
+func ParamStoreCached(paramName string, ttl time.Duration) Provider {
+	if ttl <= 0 {
+		ttl = DefaultExtensionTTL
+	}
+
+	return func(ctx context.Context) (s *discordgo.Session, err error) {
+		ctx, seg := tracing.StartSpan(ctx, "param store")
+		defer func() { seg.End(err) }()
+
+		token, err := cachedParameterValue(ctx, paramName, ttl)
 		if err != nil {
 			return nil, err
 		}
 
-		if p == nil || p.Parameter.Value == "" {
-			return nil, fmt.Errorf("parameter empty")
+		return paramStoreSession(ctx, token, nil), nil
+	}
+}
+
+// cachedParameterValue returns the cached value of the parameter, fetching it if it has expired. The cache is not
+// locked while the parameter is fetched, so fetches do not block callers of other parameters.
+func cachedParameterValue(ctx context.Context, paramName string, ttl time.Duration) (string, error) {
+	for {
+		parameterCache.Lock()
+		if p, ok := parameterCache.values[paramName]; ok && now().Before(p.expires) {
+			parameterCache.Unlock()
+			return p.value, nil
 		}
 
-		s, _ = discordgo.New("Bot " + p.Parameter.Value)
-		s.Client = xray.Client(s.Client)
+		f, inflight := parameterCache.fetches[paramName]
+		if !inflight {
+			f = &parameterFetch{done: make(chan struct{})}
+			parameterCache.fetches[paramName] = f
+		}
+		parameterCache.Unlock()
 
-		return s, nil
+		if !inflight {
+			return fetchParameter(ctx, f, paramName, ttl)
+		}
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		// the fetch failed because the caller which started it gave up, which does not apply to this caller, so it
+		// is fetched again
+		if (errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+			continue
+		}
+
+		return f.value, f.err
+	}
+}
+
+// fetchParameter fetches the parameter for the callers waiting on the fetch, caching it for the ttl
+func fetchParameter(ctx context.Context, f *parameterFetch, paramName string, ttl time.Duration) (string, error) {
+	generation := restores.Load()
+	f.value, f.err = getParameter(ctx, paramName, nil)
+
+	parameterCache.Lock()
+	// parameters fetched before the environment was restored are not cached, as they may have been fetched before the
+	// snapshot
+	if f.err == nil && restores.Load() == generation {
+		parameterCache.values[paramName] = cachedParameter{value: f.value, expires: now().Add(ttl)}
 	}
+	delete(parameterCache.fetches, paramName)
+	parameterCache.Unlock()
+	close(f.done)
+
+	return f.value, f.err
+}
+
+// getParameter gets the decrypted value of the parameter from the Parameters and Secrets Lambda Extension
+func getParameter(ctx context.Context, paramName string, client *http.Client) (string, error) {
+	if paramName == "" {
+		return "", Permanent(errors.New("empty discord token paramstore parameter name"))
+	}
+
+	value, err := paramstore.Get(ctx, paramName, client)
+	if errors.Is(err, paramstore.ErrEmpty) {
+		return "", Permanent(err)
+	}
+
+	return value, err
+}
+
+// paramStoreSession creates a session for the token, using the client if it is not nil
+func paramStoreSession(ctx context.Context, token string, client *http.Client) *discordgo.Session {
+	s, _ := discordgo.New("Bot " + token)
+	if client != nil {
+		s.Client = client
+	}
+	s.Client = tracing.Client(ctx, s.Client)
+
+	return s
 }
 
 // Cached wraps a Provider, ensuring it is only called once
@@ -57,9 +207,251 @@ func Cached(f Provider) Provider {
 	}
 }
 
+// DynamoClient gets items from a DynamoDB table. Implement it with an adapter around the AWS SDK client, which looks
+// up the item by its key and returns its string attributes, e.g.
+//
+//	func (c adapter) GetItem(ctx context.Context, table, key string) (map[string]string, error) {
+//		out, err := c.client.GetItem(ctx, &dynamodb.GetItemInput{
+//			TableName: &table,
+//			Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: key}},
+//		})
+//		if err != nil || out.Item == nil {
+//			return nil, err
+//		}
+//		var item map[string]string
+//		return item, attributevalue.UnmarshalMap(out.Item, &item)
+//	}
+//
+// A nil item without an error indicates that the item does not exist.
+type DynamoClient interface {
+	GetItem(ctx context.Context, table, key string) (map[string]string, error)
+}
+
+// DynamoTokenAttribute is the attribute of the item which holds the token read by DynamoDB
+const DynamoTokenAttribute = "token"
+
+// DynamoDB initialises the Discord Session using the token stored in the DynamoTokenAttribute of the item with the key
+// in the table
+func DynamoDB(client DynamoClient, table, key string) Provider {
+	return func(ctx context.Context) (s *discordgo.Session, err error) {
+		ctx, seg := tracing.StartSpan(ctx, "dynamodb")
+		defer func() { seg.End(err) }()
+		if table == "" || key == "" {
+			return nil, Permanent(errors.New("empty discord token dynamodb table or key"))
+		}
+
+		item, err := client.GetItem(ctx, table, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if item == nil {
+			return nil, Permanent(errors.New("item not found"))
+		}
+
+		if item[DynamoTokenAttribute] == "" {
+			return nil, Permanent(errors.New("token attribute empty"))
+		}
+
+		s, _ = discordgo.New("Bot " + item[DynamoTokenAttribute])
+		s.Client = tracing.Client(ctx, s.Client)
+
+		return s, nil
+	}
+}
+
+// now returns the current time, and is overridden in tests
+var now = time.Now
+
+// CachedFor wraps a Provider, caching the session it returns for the ttl so that the token is fetched at most once per
+// ttl, e.g. when wrapping ParamStore to reuse the session's connections between interactions. Errors are not cached.
+// The cached session is shared between concurrent calls, which is safe for making requests but means that callers must
+// not modify it. When the ttl expires the session is replaced, rather than updated, so calls which are in flight can
+// continue to use the previous session.
+func CachedFor(f Provider, ttl time.Duration) Provider {
+	var mu sync.Mutex
+	var v *discordgo.Session
+	var expires time.Time
+
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if v != nil && now().Before(expires) {
+			return v, nil
+		}
+
+		s, err := f(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		v, expires = s, now().Add(ttl)
+
+		return v, nil
+	}
+}
+
 // Static will always return the provided session.
 func Static(s *discordgo.Session) Provider {
 	return func(ctx context.Context) (*discordgo.Session, error) {
 		return s, nil
 	}
 }
+
+// placeholderTokens are token values which indicate that the token was never configured
+var placeholderTokens = map[string]bool{
+	"changeme":    true,
+	"placeholder": true,
+	"todo":        true,
+	"token":       true,
+	"xxx":         true,
+}
+
+// Normalized wraps a Provider, rebuilding the session from its token with any surrounding whitespace and existing
+// "Bot " or "Bearer " prefixes removed, e.g. when the stored token already includes the prefix which would otherwise
+// result in "Bot Bot ...". Tokens which are empty or an obvious placeholder return a permanent error. The session's
+// REST configuration is preserved, and the wrapped Provider's session is not modified.
+func Normalized(f Provider) Provider {
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		s, err := f(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, errNilSession
+		}
+
+		token, err := normalizeToken(s.Token)
+		if err != nil {
+			return nil, Permanent(err)
+		}
+
+		// the session cannot be copied as it embeds a mutex, so its REST configuration is copied to a new session
+		normalized, _ := discordgo.New("Bot " + token)
+		normalized.Client = s.Client
+		normalized.MaxRestRetries = s.MaxRestRetries
+		normalized.ShouldRetryOnRateLimit = s.ShouldRetryOnRateLimit
+		normalized.LogLevel = s.LogLevel
+		if s.UserAgent != "" {
+			normalized.UserAgent = s.UserAgent
+		}
+		if s.Ratelimiter != nil {
+			normalized.Ratelimiter = s.Ratelimiter
+		}
+
+		return normalized, nil
+	}
+}
+
+// normalizeToken strips whitespace and any authorization prefixes from the token, then validates what remains
+func normalizeToken(token string) (string, error) {
+	fields := strings.Fields(token)
+	for len(fields) > 0 && (fields[0] == "Bot" || fields[0] == "Bearer") {
+		fields = fields[1:]
+	}
+
+	switch {
+	case len(fields) == 0:
+		return "", errors.New("token empty")
+	case len(fields) > 1:
+		return "", errors.New("token contains whitespace")
+	case placeholderTokens[strings.ToLower(strings.Trim(fields[0], "<>{}$"))]:
+		return "", fmt.Errorf("token is a placeholder: %q", fields[0])
+	}
+
+	return fields[0], nil
+}
+
+// WithUserAgent wraps a Provider, setting the user agent of the sessions it returns. To avoid modifying a shared
+// session, it should be applied before any caching, e.g. CachedFor(WithUserAgent(ParamStore(name), ua), ttl).
+func WithUserAgent(f Provider, userAgent string) Provider {
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		s, err := f(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, errNilSession
+		}
+
+		s.UserAgent = userAgent
+
+		return s, nil
+	}
+}
+
+// FirstAvailable tries each Provider in order, returning the first session which is successfully resolved. If every
+// Provider fails then the errors are joined, and the joined error is only permanent if every error is permanent, as
+// retrying may succeed if any Provider failed temporarily.
+func FirstAvailable(providers ...Provider) Provider {
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		errs := make([]error, 0, len(providers))
+		for _, f := range providers {
+			s, err := f(ctx)
+			if err == nil {
+				return s, nil
+			}
+
+			errs = append(errs, err)
+		}
+
+		err := fmt.Errorf("no provider available: %w", errors.Join(errs...))
+		for _, e := range errs {
+			if !IsPermanent(e) {
+				return nil, &transientError{err: err}
+			}
+		}
+
+		return nil, Permanent(err)
+	}
+}
+
+// transientError wraps an error which may succeed if retried, even if it wraps a PermanentError
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func (e *transientError) As(target any) bool {
+	if _, ok := target.(**PermanentError); ok {
+		return false
+	}
+
+	return errors.As(e.err, target)
+}
+
+// WithRetry retries the Provider up to attempts times, doubling the backoff between each attempt. Errors marked as
+// permanent (see Permanent) are returned immediately, and retrying stops early if the next attempt would exceed the
+// context deadline.
+func WithRetry(f Provider, attempts int, backoff time.Duration) Provider {
+	return func(ctx context.Context) (s *discordgo.Session, err error) {
+		for attempt := 1; ; attempt++ {
+			s, err = f(ctx)
+			if err == nil || IsPermanent(err) || attempt >= attempts {
+				return s, err
+			}
+
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+				return nil, err
+			}
+
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, errors.Join(err, ctx.Err())
+			case <-t.C:
+			}
+
+			backoff *= 2
+		}
+	}
+}
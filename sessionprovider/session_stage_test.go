@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/bwmarrin/discordgo"
@@ -19,6 +20,9 @@ type SessionStage struct {
 	require *require.Assertions
 	session *discordgo.Session
 	err     error
+	calls   int
+	server  *url.URL
+	client  *http.Client
 }
 
 func NewSessionStage(t *testing.T) (*SessionStage, *SessionStage, *SessionStage) {
@@ -26,10 +30,20 @@ func NewSessionStage(t *testing.T) (*SessionStage, *SessionStage, *SessionStage)
 		t:       t,
 		require: require.New(t),
 	}
+	resetParameterCache(t)
 
 	return s, s, s
 }
 
+// resetParameterCache clears the parameters cached by ParamStoreCached once the test completes
+func resetParameterCache(t *testing.T) {
+	t.Cleanup(func() {
+		parameterCache.Lock()
+		defer parameterCache.Unlock()
+		clear(parameterCache.values)
+	})
+}
+
 func (s *SessionStage) and() *SessionStage {
 	return s
 }
@@ -48,11 +62,15 @@ func (s *SessionStage) a_parameter_named_x_with_value_y(x, y string) *SessionSta
 }
 
 func (s *SessionStage) param_store_will_return(f http.HandlerFunc) *SessionStage {
-	server := httptest.NewServer(f)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls++
+		f(w, r)
+	}))
 	s.t.Cleanup(server.Close)
 
 	u, err := url.Parse(server.URL)
 	s.require.NoError(err)
+	s.server = u
 
 	s.t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", u.Port())
 
@@ -67,6 +85,79 @@ func (s *SessionStage) a_new_session_from_param_store_is_requested_with_param_na
 	return s
 }
 
+func (s *SessionStage) a_new_normalized_session_from_param_store_is_requested_with_param_named(name string) *SessionStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	s.session, s.err = Normalized(ParamStore(name))(ctx)
+
+	return s
+}
+
+func (s *SessionStage) a_custom_client_which_routes_to_the_param_store() *SessionStage {
+	s.client = &http.Client{Transport: rewriteTransport{target: s.server}}
+
+	// ensure requests only reach the param store via the custom client
+	s.t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", "1")
+
+	return s
+}
+
+func (s *SessionStage) a_new_session_from_param_store_with_client_is_requested_with_param_named(name string) *SessionStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	s.session, s.err = ParamStoreWithClient(name, s.client)(ctx)
+
+	return s
+}
+
+func (s *SessionStage) a_new_session_from_param_store_with_retry_is_requested_with_param_named(name string) *SessionStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	s.session, s.err = WithRetry(ParamStore(name), 3, time.Millisecond)(ctx)
+
+	return s
+}
+
+func (s *SessionStage) sessions_from_param_store_cached_for_ttl_are_requested_n_times_with_param_named(name string, ttl time.Duration, n int) *SessionStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	f := CachedFor(ParamStore(name), ttl)
+	for range n {
+		s.session, s.err = f(ctx)
+	}
+
+	return s
+}
+
+func (s *SessionStage) sessions_from_cached_param_store_are_requested_n_times_with_param_named(name string, ttl time.Duration, n int) *SessionStage {
+	ctx, _ := xray.BeginSegment(context.Background(), "test")
+
+	f := ParamStoreCached(name, ttl)
+	for range n {
+		s.session, s.err = f(ctx)
+	}
+
+	return s
+}
+
+func (s *SessionStage) the_cache_ttl_has_elapsed(ttl time.Duration) *SessionStage {
+	current := time.Now().Add(ttl)
+	now = func() time.Time { return current }
+	s.t.Cleanup(func() { now = time.Now })
+
+	return s
+}
+
+func (s *SessionStage) the_environment_is_restored() *SessionStage {
+	Restored()
+
+	return s
+}
+
+func (s *SessionStage) the_param_store_should_have_been_called_n_times(n int) {
+	s.require.Equal(n, s.calls)
+}
+
 func (s *SessionStage) no_error_should_be_returned() *SessionStage {
 	s.require.NoError(s.err)
 
@@ -74,13 +165,17 @@ func (s *SessionStage) no_error_should_be_returned() *SessionStage {
 
 }
 
-func (s *SessionStage) the_session_has_token(token string) {
+func (s *SessionStage) the_session_has_token(token string) *SessionStage {
 	s.require.NotNil(s.session)
 	s.require.Equal(token, s.session.Token)
+
+	return s
 }
 
-func (s *SessionStage) an_error_should_be_returned(err string) {
+func (s *SessionStage) an_error_should_be_returned(err string) *SessionStage {
 	s.require.ErrorContains(s.err, err)
+
+	return s
 }
 
 func (s *SessionStage) the_param_store_server_is_unavailable() *SessionStage {
@@ -88,3 +183,16 @@ func (s *SessionStage) the_param_store_server_is_unavailable() *SessionStage {
 		w.WriteHeader(http.StatusServiceUnavailable)
 	})
 }
+
+// rewriteTransport sends all requests to the target host
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+
+	return http.DefaultTransport.RoundTrip(r)
+}
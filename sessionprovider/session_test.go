@@ -2,10 +2,20 @@ package sessionprovider
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/winebarrel/secretlamb"
 	"testing"
 )
 
@@ -80,3 +90,455 @@ func TestCached(t *testing.T) {
 	require.Equal(t, 1, count)
 	require.Equal(t, v1, v2)
 }
+
+func TestCachedFor(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	count := 0
+	f := func(ctx context.Context) (*discordgo.Session, error) {
+		count++
+
+		return &discordgo.Session{Token: fmt.Sprintf("Bot %v", count)}, nil
+	}
+
+	source := CachedFor(f, time.Minute)
+
+	// within the ttl the cached session is returned
+	v1, _ := source(context.Background())
+	current = current.Add(59 * time.Second)
+	v2, _ := source(context.Background())
+	require.Equal(t, 1, count)
+	require.Same(t, v1, v2)
+
+	// after the ttl a new session is provided
+	current = current.Add(time.Second)
+	v3, _ := source(context.Background())
+	require.Equal(t, 2, count)
+	require.Equal(t, "Bot 2", v3.Token)
+}
+
+func TestCachedFor_Error(t *testing.T) {
+	count := 0
+	f := func(ctx context.Context) (*discordgo.Session, error) {
+		count++
+		if count == 1 {
+			return nil, errors.New("unavailable")
+		}
+
+		return &discordgo.Session{Token: "Bot foo"}, nil
+	}
+
+	source := CachedFor(f, time.Minute)
+
+	_, err := source(context.Background())
+	require.Error(t, err)
+
+	s, err := source(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Bot foo", s.Token)
+	require.Equal(t, 2, count)
+}
+
+func TestSessionFromParamStore_CachedFor(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "bar")
+
+	when.
+		sessions_from_param_store_cached_for_ttl_are_requested_n_times_with_param_named("foo", time.Minute, 3)
+
+	then.
+		no_error_should_be_returned().and().
+		the_session_has_token("Bot bar").and().
+		the_param_store_should_have_been_called_n_times(1)
+}
+
+func TestSessionFromParamStoreCached(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("cached", "bar")
+
+	when.
+		sessions_from_cached_param_store_are_requested_n_times_with_param_named("cached", time.Minute, 3)
+
+	then.
+		no_error_should_be_returned().and().
+		the_session_has_token("Bot bar").and().
+		the_param_store_should_have_been_called_n_times(1)
+}
+
+func TestSessionFromParamStoreCached_Expired(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("expired", "bar")
+
+	when.
+		sessions_from_cached_param_store_are_requested_n_times_with_param_named("expired", time.Minute, 2).and().
+		the_cache_ttl_has_elapsed(time.Minute).and().
+		sessions_from_cached_param_store_are_requested_n_times_with_param_named("expired", time.Minute, 2)
+
+	then.
+		no_error_should_be_returned().and().
+		the_param_store_should_have_been_called_n_times(2)
+}
+
+func TestSessionFromParamStoreCached_Concurrent(t *testing.T) {
+	resetParameterCache(t)
+
+	// given a param store which blocks requests for the slow parameter until it is released
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "slow" {
+			calls.Add(1)
+			<-release
+		}
+
+		bs, _ := json.Marshal(secretlamb.ParameterOutput{Parameter: secretlamb.ParameterOutputParameter{Name: name, Value: name}})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bs)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", u.Port())
+
+	// when the slow parameter is requested concurrently
+	var wg sync.WaitGroup
+	sessions := make([]*discordgo.Session, 3)
+	errs := make([]error, 3)
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sessions[i], errs[i] = ParamStoreCached("slow", time.Minute)(context.Background())
+		}()
+	}
+	require.Eventually(t, func() bool { return calls.Load() > 0 }, time.Second, time.Millisecond)
+
+	// then other parameters should not be blocked by the fetch
+	s, err := ParamStoreCached("fast", time.Minute)(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Bot fast", s.Token)
+
+	// and the slow parameter should only be fetched once
+	close(release)
+	wg.Wait()
+	for i := range sessions {
+		require.NoError(t, errs[i])
+		require.Equal(t, "Bot slow", sessions[i].Token)
+	}
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestSessionFromParamStoreCached_CancelledLeader(t *testing.T) {
+	resetParameterCache(t)
+
+	// given a param store which blocks the first request until it is cancelled
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+
+		bs, _ := json.Marshal(secretlamb.ParameterOutput{Parameter: secretlamb.ParameterOutputParameter{Name: "leader", Value: "bar"}})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bs)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	t.Setenv("PARAMETERS_SECRETS_EXTENSION_HTTP_PORT", u.Port())
+
+	// when the caller which started the fetch gives up while another caller is waiting for it
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := ParamStoreCached("leader", time.Minute)(ctx)
+		leader <- err
+	}()
+	require.Eventually(t, func() bool { return calls.Load() > 0 }, time.Second, time.Millisecond)
+
+	waiter := make(chan *discordgo.Session, 1)
+	go func() {
+		s, err := ParamStoreCached("leader", time.Minute)(context.Background())
+		assert.NoError(t, err)
+		waiter <- s
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	// then the leader should fail, and the waiter should fetch the parameter again
+	require.ErrorIs(t, <-leader, context.Canceled)
+	s := <-waiter
+	require.NotNil(t, s)
+	require.Equal(t, "Bot bar", s.Token)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestFirstAvailable(t *testing.T) {
+	failing := func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, errors.New("foo")
+	}
+	s := &discordgo.Session{Token: "Bot bar"}
+
+	v, err := FirstAvailable(failing, Static(s))(context.Background())
+
+	require.NoError(t, err)
+	require.Same(t, s, v)
+}
+
+func TestFirstAvailable_AllFail(t *testing.T) {
+	failing := func(err string) Provider {
+		return func(ctx context.Context) (*discordgo.Session, error) {
+			return nil, errors.New(err)
+		}
+	}
+
+	v, err := FirstAvailable(failing("foo"), failing("bar"))(context.Background())
+
+	require.Nil(t, v)
+	require.ErrorContains(t, err, "foo")
+	require.ErrorContains(t, err, "bar")
+	require.False(t, IsPermanent(err))
+}
+
+func TestFirstAvailable_Permanent(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	failing := func(err error) Provider {
+		return func(ctx context.Context) (*discordgo.Session, error) {
+			return nil, err
+		}
+	}
+
+	// when every provider fails permanently then the error is permanent
+	_, err := FirstAvailable(failing(Permanent(errors.New("foo"))), failing(Permanent(errors.New("bar"))))(context.Background())
+	require.True(t, IsPermanent(err))
+
+	// when any provider fails temporarily then the error is not permanent, so it can be retried
+	_, err = FirstAvailable(failing(Permanent(errors.New("foo"))), failing(unavailable))(context.Background())
+	require.False(t, IsPermanent(err))
+	require.ErrorIs(t, err, unavailable)
+	require.ErrorContains(t, err, "foo")
+}
+
+func TestWithRetry(t *testing.T) {
+	calls := 0
+	f := func(ctx context.Context) (*discordgo.Session, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("unavailable")
+		}
+
+		return &discordgo.Session{Token: "Bot foo"}, nil
+	}
+
+	s, err := WithRetry(f, 3, time.Millisecond)(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, "Bot foo", s.Token)
+	require.Equal(t, 3, calls)
+}
+
+func TestWithRetry_Permanent(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "")
+
+	when.
+		a_new_session_from_param_store_with_retry_is_requested_with_param_named("foo")
+
+	then.
+		an_error_should_be_returned("parameter empty").and().
+		the_param_store_should_have_been_called_n_times(1)
+}
+
+func TestWithRetry_AlwaysUnavailable(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		the_param_store_server_is_unavailable()
+
+	when.
+		a_new_session_from_param_store_with_retry_is_requested_with_param_named("foo")
+
+	then.
+		an_error_should_be_returned("failed to get parameter - http request error").and().
+		the_param_store_should_have_been_called_n_times(3)
+}
+
+func TestWithRetry_ContextDeadline(t *testing.T) {
+	calls := 0
+	f := func(ctx context.Context) (*discordgo.Session, error) {
+		calls++
+		return nil, errors.New("unavailable")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := WithRetry(f, 5, time.Second)(ctx)
+
+	require.ErrorContains(t, err, "unavailable")
+	require.Equal(t, 1, calls)
+}
+
+func TestSessionFromParamStore_CustomClient(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "bar").and().
+		a_custom_client_which_routes_to_the_param_store()
+
+	when.
+		a_new_session_from_param_store_with_client_is_requested_with_param_named("foo")
+
+	then.
+		no_error_should_be_returned().and().
+		the_session_has_token("Bot bar")
+}
+
+type fakeDynamo map[string]map[string]string
+
+func (f fakeDynamo) GetItem(ctx context.Context, table, key string) (map[string]string, error) {
+	if table != "config" {
+		return nil, errors.New("table not found")
+	}
+
+	return f[key], nil
+}
+
+func TestDynamoDB(t *testing.T) {
+	client := fakeDynamo{
+		"found": {"token": "foo"},
+		"empty": {"token": ""},
+	}
+
+	tests := []struct {
+		name    string
+		table   string
+		key     string
+		token   string
+		wantErr string
+	}{
+		{name: "found", table: "config", key: "found", token: "Bot foo"},
+		{name: "missing item", table: "config", key: "missing", wantErr: "item not found"},
+		{name: "empty value", table: "config", key: "empty", wantErr: "token attribute empty"},
+		{name: "empty key", table: "config", key: "", wantErr: "empty discord token dynamodb table or key"},
+		{name: "client error", table: "other", key: "found", wantErr: "table not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := DynamoDB(client, tt.table, tt.key)(context.Background())
+
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				require.Nil(t, s)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.token, s.Token)
+		})
+	}
+}
+
+func TestDynamoDB_Permanent(t *testing.T) {
+	_, err := DynamoDB(fakeDynamo{}, "config", "missing")(context.Background())
+
+	require.True(t, IsPermanent(err))
+}
+
+func TestNormalized(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+		err   string
+	}{
+		{name: "prefixed", token: "Bot bar", want: "Bot bar"},
+		{name: "doubled prefix", token: "Bot Bot bar", want: "Bot bar"},
+		{name: "bearer prefix", token: "Bot Bearer bar", want: "Bot bar"},
+		{name: "surrounding whitespace", token: "Bot  bar\n", want: "Bot bar"},
+		{name: "whitespace between prefixes", token: " Bot \tBot bar ", want: "Bot bar"},
+		{name: "empty", token: "Bot ", err: "token empty"},
+		{name: "doubled prefix only", token: "Bot Bot ", err: "token empty"},
+		{name: "placeholder", token: "Bot <TOKEN>", err: `token is a placeholder: "<TOKEN>"`},
+		{name: "whitespace", token: "Bot foo bar", err: "token contains whitespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			original := &discordgo.Session{Token: tt.token, Client: client, UserAgent: "DiscordBot (https://example.com, 1.0)", MaxRestRetries: 1}
+
+			s, err := Normalized(Static(original))(context.Background())
+
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				require.True(t, IsPermanent(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, s.Token)
+			require.Same(t, client, s.Client)
+			require.Equal(t, "DiscordBot (https://example.com, 1.0)", s.UserAgent)
+			require.Equal(t, 1, s.MaxRestRetries)
+
+			// and the original session should not be modified
+			require.NotSame(t, original, s)
+			require.Equal(t, tt.token, original.Token)
+		})
+	}
+}
+
+func TestNormalized_NilSession(t *testing.T) {
+	s, err := Normalized(func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, nil
+	})(context.Background())
+
+	require.Nil(t, s)
+	require.ErrorIs(t, err, errNilSession)
+}
+
+func TestSessionFromParamStore_Normalized(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("foo", "Bot bar\n")
+
+	when.
+		a_new_normalized_session_from_param_store_is_requested_with_param_named("foo")
+
+	then.
+		no_error_should_be_returned().and().
+		the_session_has_token("Bot bar")
+}
+
+func TestWithUserAgent(t *testing.T) {
+	f := WithUserAgent(NewMock(&discordgo.Session{Token: "Bot foo"}, nil).Provider(), "DiscordBot (https://example.com, 1.0)")
+
+	s, err := f(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, "DiscordBot (https://example.com, 1.0)", s.UserAgent)
+}
+
+func TestWithUserAgent_NilSession(t *testing.T) {
+	s, err := WithUserAgent(func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, nil
+	}, "DiscordBot (https://example.com, 1.0)")(context.Background())
+
+	require.Nil(t, s)
+	require.ErrorIs(t, err, errNilSession)
+}
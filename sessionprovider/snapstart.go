@@ -0,0 +1,47 @@
+package sessionprovider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// restores counts the times the execution environment has been restored from a SnapStart snapshot
+var restores atomic.Uint64
+
+// Restored invalidates the sessions cached by RestoreSafe providers and the tokens cached by ParamStoreCached. Call it
+// from the function's SnapStart restore hook, so that sessions resolved before the snapshot (e.g. by Endpoint.Warmup)
+// are not shared by every restored environment, and tokens are fetched again after the restore.
+func Restored() {
+	restores.Add(1)
+
+	parameterCache.Lock()
+	defer parameterCache.Unlock()
+	clear(parameterCache.values)
+}
+
+// RestoreSafe wraps the Provider built by newProvider, rebuilding it on the first call after the environment has been
+// restored from a SnapStart snapshot (see Restored). This discards any state held by the provider, such as the session
+// cached by Cached or CachedFor, e.g.
+//
+//	sessionprovider.RestoreSafe(func() sessionprovider.Provider {
+//		return sessionprovider.Cached(sessionprovider.ParamStore("token"))
+//	})
+func RestoreSafe(newProvider func() Provider) Provider {
+	var mu sync.Mutex
+	f := newProvider()
+	generation := restores.Load()
+
+	return func(ctx context.Context) (*discordgo.Session, error) {
+		mu.Lock()
+		if current := restores.Load(); current != generation {
+			f, generation = newProvider(), current
+		}
+		provider := f
+		mu.Unlock()
+
+		return provider(ctx)
+	}
+}
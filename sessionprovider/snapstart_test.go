@@ -0,0 +1,49 @@
+package sessionprovider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreSafe(t *testing.T) {
+	count := 0
+	f := RestoreSafe(func() Provider {
+		return Cached(func(ctx context.Context) (*discordgo.Session, error) {
+			count++
+			return &discordgo.Session{Token: fmt.Sprintf("Bot %v", count)}, nil
+		})
+	})
+
+	v1, _ := f(context.Background())
+	v2, _ := f(context.Background())
+	require.Same(t, v1, v2)
+
+	// when the environment is restored then the cached session is discarded
+	Restored()
+
+	v3, err := f(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Bot 2", v3.Token)
+	require.Equal(t, 2, count)
+}
+
+func TestSessionFromParamStoreCached_Restored(t *testing.T) {
+	given, when, then := NewSessionStage(t)
+
+	given.
+		a_parameter_named_x_with_value_y("restored", "bar")
+
+	when.
+		sessions_from_cached_param_store_are_requested_n_times_with_param_named("restored", time.Minute, 2).and().
+		the_environment_is_restored().and().
+		sessions_from_cached_param_store_are_requested_n_times_with_param_named("restored", time.Minute, 2)
+
+	then.
+		no_error_should_be_returned().and().
+		the_param_store_should_have_been_called_n_times(2)
+}
@@ -1,8 +1,15 @@
 package bot_lambda
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
 	"os"
 	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -10,3 +17,30 @@ func TestMain(m *testing.M) {
 
 	m.Run()
 }
+
+func mustMarshal(t *testing.T, v any) []byte {
+	bs, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return bs
+}
+
+// send sends the interaction to the endpoint as an unsigned function URL request
+func send(t *testing.T, e *Endpoint, i *discordgo.Interaction) *events.LambdaFunctionURLResponse {
+	return sendWithHeaders(t, e, i, nil)
+}
+
+// sendWithHeaders sends the interaction to the endpoint as an unsigned function URL request with the headers
+func sendWithHeaders(t *testing.T, e *Endpoint, i *discordgo.Interaction, headers map[string]string) *events.LambdaFunctionURLResponse {
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Headers: headers,
+		Body:    string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: i})),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	return res
+}
@@ -0,0 +1,61 @@
+package bot_lambda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+)
+
+// SubcommandHandler handles a subcommand, receiving the options passed to the subcommand.
+type SubcommandHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) error
+
+// WithSubcommand registers a handler for a subcommand of a chat command, such as "set" in "/config set". Subcommands
+// within a subcommand group are registered with the group and subcommand names separated by a space, such as
+// "user set" in "/config user set". The command is registered with the router when its first subcommand is registered.
+func (e *Endpoint) WithSubcommand(command string, subcommand string, handler SubcommandHandler) *Endpoint {
+	if _, ok := e.subcommands[command]; !ok {
+		e.subcommands[command] = map[string]SubcommandHandler{}
+		e.WithChatApplicationCommand(command, e.subcommandHandler(command))
+	}
+
+	e.subcommands[command][subcommand] = handler
+
+	return e
+}
+
+// subcommandHandler returns a command handler which dispatches to the command's subcommand handlers
+func (e *Endpoint) subcommandHandler(command string) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		path, options := subcommandPath(data.Options)
+
+		h, ok := e.subcommands[command][path]
+		if !ok {
+			return fmt.Errorf("no handler for subcommand %q of command %q", path, command)
+		}
+
+		return h(ctx, s, i, options)
+	}
+}
+
+// subcommandPath returns the path of the subcommand invoked, and the options passed to it. Discord sends the subcommand
+// (or subcommand group) as the command's only option.
+func subcommandPath(options []*discordgo.ApplicationCommandInteractionDataOption) (string, []*discordgo.ApplicationCommandInteractionDataOption) {
+	if len(options) == 0 {
+		return "", nil
+	}
+
+	switch opt := options[0]; opt.Type {
+	case discordgo.ApplicationCommandOptionSubCommandGroup:
+		if len(opt.Options) == 0 {
+			return opt.Name, nil
+		}
+
+		return opt.Name + " " + opt.Options[0].Name, opt.Options[0].Options
+	case discordgo.ApplicationCommandOptionSubCommand:
+		return opt.Name, opt.Options
+	default:
+		return "", options
+	}
+}
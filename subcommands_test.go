@@ -0,0 +1,76 @@
+package bot_lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSubcommand(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	var calls []string
+	var got []*discordgo.ApplicationCommandInteractionDataOption
+	handler := func(name string) SubcommandHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) error {
+			calls = append(calls, name)
+			got = options
+			return nil
+		}
+	}
+	e.WithSubcommand("config", "get", handler("get")).
+		WithSubcommand("config", "user set", handler("user set"))
+
+	value := &discordgo.ApplicationCommandInteractionDataOption{Name: "value", Type: discordgo.ApplicationCommandOptionString, Value: "bar"}
+
+	// when a subcommand within a group is invoked
+	send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        "config",
+			CommandType: discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name: "user",
+				Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+					Name:    "set",
+					Type:    discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{value},
+				}},
+			}},
+		},
+	})
+
+	// then the subcommand handler should be called with the subcommand's options
+	assert.Equal(t, []string{"user set"}, calls)
+	require.Len(t, got, 1)
+	assert.Equal(t, "bar", got[0].StringValue())
+
+	// when a top level subcommand is invoked
+	send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        "config",
+			CommandType: discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name: "get",
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+			}},
+		},
+	})
+
+	assert.Equal(t, []string{"user set", "get"}, calls)
+}
+
+func TestSubcommandPath(t *testing.T) {
+	path, options := subcommandPath([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "value", Type: discordgo.ApplicationCommandOptionString},
+	})
+
+	assert.Empty(t, path)
+	assert.Len(t, options, 1)
+}
@@ -0,0 +1,35 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithHandlerTimeout sets a deadline on the context passed to middleware and handlers, so that slow handlers are
+// cancelled rather than running until the Lambda function times out. The deadline is never later than the Lambda
+// invocation's own deadline. Discord requires an initial response within 3 seconds, so handlers which may exceed
+// this should be deferred (see WithDeferredResponseEnabled), in which case the deferred response is always sent before
+// the deadline starts.
+func WithHandlerTimeout(d time.Duration) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.handlerTimeout = d
+	}
+}
+
+// handlerContext returns the context for the handler, with the handler timeout applied if configured. The context
+// inherits the Lambda invocation's deadline from the parent.
+func (e *Endpoint) handlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.handlerTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, e.handlerTimeout)
+}
+
+// checkDeadline logs a warning if the handler context's deadline was exceeded
+func (e *Endpoint) checkDeadline(ctx context.Context) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		e.logger(ctx).Warn("Handler deadline exceeded")
+	}
+}
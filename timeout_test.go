@@ -0,0 +1,80 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineEndpoint returns an endpoint which records the deadline of the foo command handler's context
+func deadlineEndpoint(t *testing.T, options ...Option) (*Endpoint, *time.Time) {
+	e, _ := commandEndpoint(t, options...)
+
+	var deadline time.Time
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		var ok bool
+		deadline, ok = ctx.Deadline()
+		assert.True(t, ok)
+
+		return nil
+	})
+
+	return e, &deadline
+}
+
+func TestWithHandlerTimeout(t *testing.T) {
+	e, deadline := deadlineEndpoint(t, WithHandlerTimeout(2*time.Second))
+
+	start := time.Now()
+	send(t, e, fooCommand())
+
+	assert.WithinDuration(t, start.Add(2*time.Second), *deadline, 100*time.Millisecond)
+}
+
+func TestWithHandlerTimeout_LambdaDeadline(t *testing.T) {
+	e, deadline := deadlineEndpoint(t, WithHandlerTimeout(time.Minute))
+
+	// when the Lambda invocation's deadline is sooner than the handler timeout
+	lambdaDeadline := time.Now().Add(time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), lambdaDeadline)
+	defer cancel()
+
+	_, err := e.HandleRequest(ctx, &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+	require.NoError(t, err)
+
+	// then the handler's deadline should be the Lambda deadline
+	assert.Equal(t, lambdaDeadline, *deadline)
+}
+
+func TestWithHandlerTimeout_Deferred(t *testing.T) {
+	server, received := callbackServer(t)
+	e, _ := commandEndpoint(t, WithHandlerTimeout(time.Nanosecond), WithDeferredResponseEnabled(true), WithDiscordEndpoint(server.URL))
+
+	// when the handler times out
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+
+	// then the deferred response should have already been sent
+	require.NoError(t, err)
+	require.Len(t, *received, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, (*received)[0].Type)
+}
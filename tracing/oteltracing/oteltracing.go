@@ -0,0 +1,76 @@
+// Package oteltracing provides an OpenTelemetry implementation of tracing.Tracer.
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/elliotwms/bot-lambda/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a tracing.Tracer which creates spans using the OpenTelemetry tracer.
+// HTTP clients are returned unchanged; wrap them with otelhttp to trace outbound requests.
+func New(t trace.Tracer) tracing.Tracer {
+	return tracer{t: t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) StartSpan(ctx context.Context, name string) (context.Context, tracing.Span) {
+	ctx, s := t.t.Start(ctx, name)
+
+	return ctx, span{s: s}
+}
+
+func (t tracer) Client(c *http.Client) *http.Client {
+	return c
+}
+
+// Extract continues the trace using the global propagator.
+func (t tracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	carrier := make(propagation.MapCarrier, len(headers))
+	for k, v := range headers {
+		carrier.Set(strings.ToLower(k), v)
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (s span) SetAttribute(key string, value any) {
+	switch v := value.(type) {
+	case string:
+		s.s.SetAttributes(attribute.String(key, v))
+	case int:
+		s.s.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.s.SetAttributes(attribute.Int64(key, v))
+	case bool:
+		s.s.SetAttributes(attribute.Bool(key, v))
+	case float64:
+		s.s.SetAttributes(attribute.Float64(key, v))
+	default:
+		s.s.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.s.RecordError(err)
+		s.s.SetStatus(codes.Error, err.Error())
+	}
+
+	s.s.End()
+}
@@ -0,0 +1,49 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordingTracer struct {
+	noop.Tracer
+	names []string
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	r.names = append(r.names, name)
+
+	return r.Tracer.Start(ctx, name, opts...)
+}
+
+func TestTracer(t *testing.T) {
+	r := &recordingTracer{}
+	tracer := New(r)
+
+	ctx, s := tracer.StartSpan(context.Background(), "foo")
+	s.SetAttribute("type", 2)
+	_, child := tracer.StartSpan(ctx, "bar")
+	child.End(errors.New("baz"))
+	s.End(nil)
+
+	require.Equal(t, []string{"foo", "bar"}, r.names)
+}
+
+func TestTracer_Extract(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx := New(&recordingTracer{}).Extract(context.Background(), map[string]string{
+		"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsRemote())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+}
@@ -0,0 +1,168 @@
+// Package tracing provides a small abstraction over the tracing SDK used by the endpoint and session providers.
+// AWS X-Ray is used by default.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-xray-sdk-go/header"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// Tracer starts spans and instruments HTTP clients.
+type Tracer interface {
+	// StartSpan starts a new span as a child of any span in the context.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+	// Client instruments the client so outbound requests are traced.
+	Client(c *http.Client) *http.Client
+	// Extract continues the trace propagated by the incoming request's headers, if present.
+	Extract(ctx context.Context, headers map[string]string) context.Context
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttribute adds an indexed attribute (or annotation) to the span.
+	SetAttribute(key string, value any)
+	// End ends the span, recording the error if non-nil.
+	End(err error)
+}
+
+type tracerKey struct{}
+
+// ContextWithTracer returns a copy of the context carrying the tracer, which is used by StartSpan and Client.
+func ContextWithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// FromContext returns the tracer carried by the context, defaulting to XRay.
+func FromContext(ctx context.Context) Tracer {
+	if t, ok := ctx.Value(tracerKey{}).(Tracer); ok {
+		return t
+	}
+
+	return XRay()
+}
+
+// StartSpan starts a new span using the tracer carried by the context.
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return FromContext(ctx).StartSpan(ctx, name)
+}
+
+// Client instruments the client using the tracer carried by the context.
+func Client(ctx context.Context, c *http.Client) *http.Client {
+	return FromContext(ctx).Client(c)
+}
+
+// XRay returns a Tracer which creates AWS X-Ray subsegments.
+func XRay() Tracer {
+	return xrayTracer{}
+}
+
+type xrayTracer struct{}
+
+// StartSpan begins a subsegment, unless the trace is not sampled in which case no subsegment is created.
+func (xrayTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	if !sampled(ctx) {
+		return ctx, noopSpan{}
+	}
+
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+
+	return ctx, xraySpan{seg: seg}
+}
+
+// sampled returns false if the parent segment or incoming trace header has decided the trace should not be sampled
+func sampled(ctx context.Context) bool {
+	if seg := xray.GetSegment(ctx); seg != nil {
+		return seg.Sampled
+	}
+
+	if v, ok := ctx.Value(xray.LambdaTraceHeaderKey).(string); ok {
+		return header.FromString(v).SamplingDecision != header.NotSampled
+	}
+
+	return true
+}
+
+func (xrayTracer) Client(c *http.Client) *http.Client {
+	return xray.Client(c)
+}
+
+// Extract seeds the context with the X-Amzn-Trace-Id header unless the context already carries a segment or trace
+// header (as it does when the Lambda runtime provides one).
+func (xrayTracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	if xray.GetSegment(ctx) != nil || ctx.Value(xray.LambdaTraceHeaderKey) != nil {
+		return ctx
+	}
+
+	for k, v := range headers {
+		if strings.EqualFold(k, xray.TraceIDHeaderKey) && v != "" {
+			return context.WithValue(ctx, xray.LambdaTraceHeaderKey, v)
+		}
+	}
+
+	return ctx
+}
+
+type xraySpan struct {
+	seg *xray.Segment
+}
+
+// SetAttribute adds an annotation to the segment. The segment is nil if there was no parent segment in the context.
+func (s xraySpan) SetAttribute(key string, value any) {
+	if s.seg == nil {
+		return
+	}
+
+	_ = s.seg.AddAnnotation(key, value)
+}
+
+func (s xraySpan) End(err error) {
+	if s.seg == nil {
+		return
+	}
+
+	s.seg.Close(err)
+}
+
+// Prefixed returns a Tracer which prefixes the names of the spans started by the tracer, e.g. to distinguish the spans
+// of multiple endpoints in one service.
+func Prefixed(t Tracer, prefix string) Tracer {
+	return prefixedTracer{Tracer: t, prefix: prefix}
+}
+
+type prefixedTracer struct {
+	Tracer
+	prefix string
+}
+
+func (t prefixedTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return t.Tracer.StartSpan(ctx, t.prefix+name)
+}
+
+// Noop returns a Tracer which does nothing.
+func Noop() Tracer {
+	return noopTracer{}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Client(c *http.Client) *http.Client {
+	return c
+}
+
+func (noopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+
+func (noopSpan) End(error) {}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/aws/aws-xray-sdk-go/xraylog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXRay_MissingSegment(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+	xray.SetLogger(xraylog.NullLogger)
+
+	// given a context without a segment
+	ctx := context.Background()
+
+	require.NotPanics(t, func() {
+		_, s := XRay().StartSpan(ctx, "foo")
+		s.SetAttribute("foo", "bar")
+		s.End(errors.New("baz"))
+	})
+}
+
+func TestXRay_Disabled(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "true")
+
+	require.NotPanics(t, func() {
+		_, s := XRay().StartSpan(context.Background(), "foo")
+		s.SetAttribute("foo", "bar")
+		s.End(nil)
+	})
+}
+
+func TestNoop(t *testing.T) {
+	c := &http.Client{}
+	ctx := ContextWithTracer(context.Background(), Noop())
+
+	spanCtx, s := StartSpan(ctx, "foo")
+	s.SetAttribute("foo", "bar")
+	s.End(nil)
+
+	require.Equal(t, ctx, spanCtx)
+	require.Same(t, c, Client(ctx, c))
+	require.Nil(t, xray.GetSegment(spanCtx))
+}
+
+func TestXRay_Unsampled(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	// given an unsampled parent segment
+	ctx, parent := xray.BeginSegment(context.Background(), "test")
+	parent.Sampled = false
+	defer parent.Close(nil)
+
+	// then starting a span should not create a subsegment
+	spanCtx, s := XRay().StartSpan(ctx, "foo")
+	s.SetAttribute("foo", "bar")
+	s.End(nil)
+
+	require.Same(t, parent, xray.GetSegment(spanCtx))
+	require.Empty(t, parent.Subsegments)
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		_, s := XRay().StartSpan(ctx, "foo")
+		s.End(nil)
+	}))
+}
+
+func TestXRay_UnsampledTraceHeader(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	ctx := context.WithValue(context.Background(), xray.LambdaTraceHeaderKey, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0")
+
+	spanCtx, _ := XRay().StartSpan(ctx, "foo")
+
+	require.Nil(t, xray.GetSegment(spanCtx))
+}
+
+func TestXRay_Sampled(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	ctx, parent := xray.BeginSegment(context.Background(), "test")
+	parent.Sampled = true
+	defer parent.Close(nil)
+
+	spanCtx, s := XRay().StartSpan(ctx, "foo")
+	defer s.End(nil)
+
+	require.NotSame(t, parent, xray.GetSegment(spanCtx))
+}
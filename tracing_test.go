@@ -0,0 +1,204 @@
+package bot_lambda
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/tracing"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotations(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	var annotations map[string]interface{}
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		seg := xray.GetSegment(ctx)
+		require.NotNil(t, seg)
+		seg.Lock()
+		annotations = seg.Annotations
+		seg.Unlock()
+
+		return nil
+	})
+
+	ctx, root := xray.BeginSegment(context.Background(), "test")
+	root.Sampled = true
+	defer root.Close(nil)
+
+	_, err := e.HandleRequest(ctx, &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionApplicationCommand,
+			GuildID:   "guild_id",
+			ChannelID: "channel_id",
+			Data:      discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+		}})),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"type":       int(discordgo.InteractionApplicationCommand),
+		"command":    "foo",
+		"guild_id":   "guild_id",
+		"channel_id": "channel_id",
+	}, annotations)
+}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, tracing.Span) {
+	s := &recordingSpan{name: name, attributes: map[string]any{}}
+	r.spans = append(r.spans, s)
+
+	return ctx, s
+}
+
+func (r *recordingTracer) Client(c *http.Client) *http.Client {
+	return c
+}
+
+func (r *recordingTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (r *recordingTracer) names() []string {
+	names := make([]string, len(r.spans))
+	for i, s := range r.spans {
+		names[i] = s.name
+	}
+
+	return names
+}
+
+type recordingSpan struct {
+	name       string
+	attributes map[string]any
+	ended      bool
+	err        error
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+func TestWithTracer(t *testing.T) {
+	r := &recordingTracer{}
+	e := New(nil, WithLogger(slogt.New(t)), WithTracer(r))
+
+	var providerTracer tracing.Tracer
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		providerTracer = tracing.FromContext(ctx)
+		return &discordgo.Session{}, nil
+	})
+
+	send(t, e, fooCommand())
+
+	assert.Equal(t, []string{"handle request", "handle", "verify", "handle interaction"}, r.names())
+	for _, s := range r.spans {
+		assert.True(t, s.ended, s.name)
+	}
+	assert.Equal(t, int(discordgo.InteractionApplicationCommand), r.spans[3].attributes["type"])
+	assert.Same(t, r, providerTracer)
+}
+
+func TestWithSegmentPrefix(t *testing.T) {
+	r := &recordingTracer{}
+	e := New(nil, WithLogger(slogt.New(t)), WithTracer(r), WithSegmentPrefix("bot-a "))
+
+	var providerTracer tracing.Tracer
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		providerTracer = tracing.FromContext(ctx)
+		_, span := providerTracer.StartSpan(ctx, "provide session")
+		span.End(nil)
+
+		return &discordgo.Session{}, nil
+	})
+
+	send(t, e, fooCommand())
+
+	assert.Equal(t, []string{
+		"bot-a handle request",
+		"bot-a handle",
+		"bot-a verify",
+		"bot-a handle interaction",
+		"bot-a provide session",
+	}, r.names())
+}
+
+func TestWithTracingDisabled(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	e := New(nil, WithLogger(slogt.New(t)), WithTracingDisabled())
+
+	var seg *xray.Segment
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		seg = xray.GetSegment(ctx)
+		return nil
+	})
+
+	// when an interaction is received without a parent segment
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then it should be handled without creating any segments
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Nil(t, seg)
+}
+
+func TestTraceHeaderPropagation(t *testing.T) {
+	t.Setenv("AWS_XRAY_SDK_DISABLED", "false")
+
+	// the interaction response endpoint records the trace header
+	var traceHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceHeader = r.Header.Get(xray.TraceIDHeaderKey)
+	}))
+	t.Cleanup(server.Close)
+
+	e := New(nil, WithLogger(slogt.New(t)), WithDeferredResponseEnabled(true), WithDiscordEndpoint(server.URL))
+	e.WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+		return nil
+	})
+
+	// when a request is received with a trace header and no segment in the context
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Headers: map[string]string{
+			"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			ID:    "interaction_id",
+			Type:  discordgo.InteractionApplicationCommand,
+			Token: "interaction_token",
+			Data:  discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+		}})),
+	})
+	require.NoError(t, err)
+
+	// then the outbound request should continue the inbound trace
+	assert.Contains(t, traceHeader, "Root=1-5759e988-bd862e3fe1be46a994272793")
+	assert.NotContains(t, traceHeader, "Parent=53995c3f42cd8ad8", "parent should be the outbound subsegment")
+}
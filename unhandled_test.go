@@ -0,0 +1,134 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUnhandledInteractionHandler(t *testing.T) {
+	var unhandled *discordgo.InteractionCreate
+	e, calls := commandEndpoint(t, WithUnhandledInteractionHandler(func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+		unhandled = i
+		return &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "Unknown command"},
+		}
+	}))
+
+	// when an unregistered command is received
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "bar", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then the fallback should respond
+	require.NotNil(t, unhandled)
+	assert.Equal(t, "bar", unhandled.ApplicationCommandData().Name)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, "Unknown command", v.Data.Content)
+
+	// when a registered command is received then the fallback is not used
+	unhandled = nil
+	send(t, e, fooCommand())
+	assert.Nil(t, unhandled)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWithUnhandledInteractionHandler_RegisteredAutocomplete(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var unhandled int
+	e, _ := commandEndpoint(t,
+		WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		WithUnhandledInteractionHandler(func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+			unhandled++
+			return &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Content: "Unknown command"},
+			}
+		}),
+	)
+
+	// when an autocomplete interaction for a registered command is received
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommandAutocomplete,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then it should be answered with no choices rather than by the fallback
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var v *discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &v))
+	assert.Equal(t, discordgo.InteractionApplicationCommandAutocompleteResult, v.Type)
+	assert.Equal(t, 0, unhandled)
+	assert.Nil(t, findRecord(logRecords(t, buf), "Unhandled interaction"))
+}
+
+func TestUnhandledInteraction_LogsWarning(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))
+
+	res := send(t, e, fooCommand())
+
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	r := findRecord(logRecords(t, buf), "Unhandled interaction")
+	require.NotNil(t, r)
+	assert.Equal(t, "WARN", r["level"])
+}
+
+func TestUnknownInteractionType(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e, calls := commandEndpoint(t, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))
+
+	// when an interaction type unknown to the endpoint is received
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionType(99),
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then it should be acknowledged without being handled
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, 0, *calls)
+	r := findRecord(logRecords(t, buf), "Unknown interaction type")
+	require.NotNil(t, r)
+	assert.EqualValues(t, 99, r["type"])
+}
+
+func TestWithInteractionTypeHandler(t *testing.T) {
+	var handled []string
+	catchAll := func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+		if i.Type == discordgo.InteractionMessageComponent {
+			handled = append(handled, i.MessageComponentData().CustomID)
+		} else {
+			handled = append(handled, i.ApplicationCommandData().Name)
+		}
+
+		return AckResponse()
+	}
+
+	e, calls := commandEndpoint(t,
+		WithInteractionTypeHandler(discordgo.InteractionMessageComponent, catchAll),
+		WithInteractionTypeHandler(discordgo.InteractionApplicationCommand, catchAll),
+	)
+	e.WithComponentHandler("vote", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, params []string) (*discordgo.InteractionResponse, error) {
+		return AckResponse(), nil
+	})
+
+	// when unmatched and matched components and commands are received
+	send(t, e, componentInteraction("other"))
+	send(t, e, componentInteraction("vote:yes"))
+	send(t, e, fooCommand())
+
+	// then the catch-all should only be called for the unmatched component
+	assert.Equal(t, []string{"other"}, handled)
+	assert.Equal(t, 1, *calls)
+}
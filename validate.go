@@ -0,0 +1,79 @@
+package bot_lambda
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAutocompleteChoices is the maximum number of choices Discord accepts in an autocomplete result.
+const maxAutocompleteChoices = 25
+
+// ValidateResponse checks that the response type is one Discord accepts for the interaction, and that the response
+// contains the data required by its type. Discord otherwise rejects invalid responses with an opaque error.
+// See https://discord.com/developers/docs/interactions/receiving-and-responding#responding-to-an-interaction
+func ValidateResponse(i *discordgo.InteractionCreate, res *discordgo.InteractionResponse) error {
+	if !slices.Contains(allowedResponseTypes(i), res.Type) {
+		return fmt.Errorf("response type %d is not valid for interaction type %s", res.Type, i.Type)
+	}
+
+	switch res.Type {
+	case discordgo.InteractionResponseModal:
+		if res.Data == nil || res.Data.CustomID == "" || res.Data.Title == "" || len(res.Data.Components) == 0 {
+			return errors.New("modal response requires a custom ID, title and components")
+		}
+	case discordgo.InteractionResponseUpdateMessage:
+		if res.Data == nil {
+			return errors.New("update message response requires data, use a deferred message update to acknowledge without updating")
+		}
+	case discordgo.InteractionApplicationCommandAutocompleteResult:
+		if res.Data != nil && len(res.Data.Choices) > maxAutocompleteChoices {
+			return fmt.Errorf("autocomplete response has %d choices, maximum is %d", len(res.Data.Choices), maxAutocompleteChoices)
+		}
+	}
+
+	return nil
+}
+
+// allowedResponseTypes returns the response types Discord accepts for the interaction
+func allowedResponseTypes(i *discordgo.InteractionCreate) []discordgo.InteractionResponseType {
+	switch i.Type {
+	case discordgo.InteractionPing:
+		return []discordgo.InteractionResponseType{discordgo.InteractionResponsePong}
+	case discordgo.InteractionApplicationCommand:
+		return []discordgo.InteractionResponseType{
+			discordgo.InteractionResponseChannelMessageWithSource,
+			discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			discordgo.InteractionResponseModal,
+			InteractionResponsePremiumRequired,
+		}
+	case discordgo.InteractionMessageComponent:
+		return []discordgo.InteractionResponseType{
+			discordgo.InteractionResponseChannelMessageWithSource,
+			discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			discordgo.InteractionResponseDeferredMessageUpdate,
+			discordgo.InteractionResponseUpdateMessage,
+			discordgo.InteractionResponseModal,
+			InteractionResponsePremiumRequired,
+		}
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		return []discordgo.InteractionResponseType{discordgo.InteractionApplicationCommandAutocompleteResult}
+	case discordgo.InteractionModalSubmit:
+		types := []discordgo.InteractionResponseType{
+			discordgo.InteractionResponseChannelMessageWithSource,
+			discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			InteractionResponsePremiumRequired,
+		}
+
+		// modals opened from a component can update the component's message
+		if i.Message != nil {
+			types = append(types, discordgo.InteractionResponseDeferredMessageUpdate, discordgo.InteractionResponseUpdateMessage)
+		}
+
+		return types
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResponse(t *testing.T) {
+	message := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "hello"},
+	}
+	modal := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   "modal",
+			Title:      "Modal",
+			Components: []discordgo.MessageComponent{discordgo.ActionsRow{}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		i       *discordgo.Interaction
+		res     *discordgo.InteractionResponse
+		wantErr bool
+	}{
+		{"pong to ping", &discordgo.Interaction{Type: discordgo.InteractionPing}, &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong}, false},
+		{"message to ping", &discordgo.Interaction{Type: discordgo.InteractionPing}, message, true},
+		{"message to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, message, false},
+		{"modal to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, modal, false},
+		{"pong to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong}, true},
+		{"update to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, AckResponse(), true},
+		{"autocomplete to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, AutocompleteResponse(), true},
+		{"premium required to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, PremiumRequiredResponse(), false},
+		{"premium required to autocomplete", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommandAutocomplete}, PremiumRequiredResponse(), true},
+		{"update to component", &discordgo.Interaction{Type: discordgo.InteractionMessageComponent}, AckResponse(), false},
+		{"update message to component", &discordgo.Interaction{Type: discordgo.InteractionMessageComponent}, UpdateMessageResponse(&discordgo.InteractionResponseData{Content: "hello"}), false},
+		{"update message to command", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, UpdateMessageResponse(&discordgo.InteractionResponseData{Content: "hello"}), true},
+		{"update message without data", &discordgo.Interaction{Type: discordgo.InteractionMessageComponent}, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage}, true},
+		{"autocomplete to autocomplete", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommandAutocomplete}, AutocompleteResponse(), false},
+		{"message to autocomplete", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommandAutocomplete}, message, true},
+		{"modal to modal submit", &discordgo.Interaction{Type: discordgo.InteractionModalSubmit}, modal, true},
+		{"update to modal submit", &discordgo.Interaction{Type: discordgo.InteractionModalSubmit}, AckResponse(), true},
+		{"update to component modal submit", &discordgo.Interaction{Type: discordgo.InteractionModalSubmit, Message: &discordgo.Message{}}, AckResponse(), false},
+		{"modal without title", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &discordgo.InteractionResponseData{CustomID: "modal"}}, true},
+		{"too many choices", &discordgo.Interaction{Type: discordgo.InteractionApplicationCommandAutocomplete}, AutocompleteResponse(make([]*discordgo.ApplicationCommandOptionChoice, 26)...), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResponse(&discordgo.InteractionCreate{Interaction: tt.i}, tt.res)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHandle_InvalidResponse(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e, _ := commandEndpoint(t,
+		WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		WithMiddleware(responding(AckResponse())),
+	)
+
+	// when a handler responds to a command with a response type only valid for components
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: fooCommand()})),
+	})
+
+	// then the response should be rejected and logged
+	require.Error(t, err)
+	r := findRecord(logRecords(t, buf), "Invalid interaction response")
+	require.NotNil(t, r)
+	assert.EqualValues(t, discordgo.InteractionApplicationCommand, r["interaction_type"])
+	assert.EqualValues(t, discordgo.InteractionResponseDeferredMessageUpdate, r["response_type"])
+}
+
+func TestHandle_UnansweredAutocomplete(t *testing.T) {
+	e, calls := commandEndpoint(t)
+
+	// when an autocomplete interaction for a registered command is not answered by any handler
+	res := send(t, e, &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommandAutocomplete,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "foo", CommandType: discordgo.ChatApplicationCommand},
+	})
+
+	// then it should be responded to with no choices
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var body discordgo.InteractionResponse
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &body))
+	assert.Equal(t, discordgo.InteractionApplicationCommandAutocompleteResult, body.Type)
+	assert.Equal(t, 0, *calls)
+}
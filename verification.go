@@ -0,0 +1,130 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// ErrNoPublicKey is returned when verification is required but the endpoint has no public key. See
+// WithRequireVerification.
+var ErrNoPublicKey = errors.New("signature verification is required but no public key is configured")
+
+// WithRequireVerification requires requests to be verified, so that an endpoint which is missing its public key (e.g.
+// because it failed to load) fails every request rather than silently accepting unverified requests. Without this,
+// verification is skipped when no public key is provided, which is useful in tests.
+func WithRequireVerification(required bool) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.requireVerification = required
+	}
+}
+
+// VerificationFailureReason is the reason a request failed signature verification
+type VerificationFailureReason int
+
+const (
+	// ReasonMissingSignature is returned when the signature header is missing or cannot be parsed
+	ReasonMissingSignature VerificationFailureReason = iota + 1
+	// ReasonMissingTimestamp is returned when the timestamp header is missing
+	ReasonMissingTimestamp
+	// ReasonBadEncoding is returned when the signature cannot be decoded, e.g. it is not valid hex
+	ReasonBadEncoding
+	// ReasonInvalidSignature is returned when the signature does not match the request
+	ReasonInvalidSignature
+	// ReasonStaleTimestamp is returned when the timestamp is outside the maximum age (see WithMaxTimestampAge) or cannot
+	// be parsed
+	ReasonStaleTimestamp
+	// ReasonReplayed is returned when the request has already been seen (see WithReplayProtection)
+	ReasonReplayed
+)
+
+func (r VerificationFailureReason) String() string {
+	switch r {
+	case ReasonMissingSignature:
+		return "missing signature"
+	case ReasonMissingTimestamp:
+		return "missing timestamp"
+	case ReasonBadEncoding:
+		return "bad encoding"
+	case ReasonInvalidSignature:
+		return "invalid signature"
+	case ReasonStaleTimestamp:
+		return "stale timestamp"
+	case ReasonReplayed:
+		return "replayed"
+	default:
+		return "unknown"
+	}
+}
+
+// VerificationError is returned when a request fails signature verification, so that callers such as a
+// VerificationFailureHandler can switch on the Reason.
+type VerificationError struct {
+	Reason VerificationFailureReason
+	Err    error
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verificationError wraps the error as a VerificationError with the reason
+func verificationError(reason VerificationFailureReason, err error) error {
+	return &VerificationError{Reason: reason, Err: err}
+}
+
+type verifiedKey struct{}
+
+// IsVerified returns true if the request being handled passed signature verification, or false if verification was
+// skipped because the endpoint has no public key (e.g. in tests). Handlers can use it to refuse sensitive actions when
+// running unverified. Interactions handled asynchronously, e.g. from SQS, are not verified by the endpoint.
+func IsVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(verifiedKey{}).(bool)
+
+	return verified
+}
+
+// VerificationFailureHandler is called when a request fails signature verification, with the request headers and the
+// reason verification failed. It can be used to record failures for alerting, as a spike in failed verifications can
+// be a sign of probing.
+type VerificationFailureHandler func(ctx context.Context, headers map[string]string, reason error)
+
+// WithVerificationFailureHandler calls the handler when a request fails signature verification, after the failure has
+// been logged. The request is rejected with a 401 regardless.
+func WithVerificationFailureHandler(h VerificationFailureHandler) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.verificationFailure = h
+	}
+}
+
+// verificationFailed logs a structured audit record of the verification failure and calls the failure handler
+func (e *Endpoint) verificationFailed(ctx context.Context, headers map[string]string, reason error) {
+	info := requestInfoFromContext(ctx)
+
+	e.logger(ctx).Error(
+		"Failed to verify signature",
+		slog.String("error", reason.Error()),
+		slog.String("reason", verificationReason(reason).String()),
+		slog.String("source_ip", info.sourceIP),
+		slog.String("user_agent", info.userAgent),
+		slog.String("signature_timestamp", headerValue(headers, headerTimestamp)),
+	)
+
+	if e.verificationFailure != nil {
+		e.verificationFailure(ctx, headers, reason)
+	}
+}
+
+// verificationReason returns the reason of the VerificationError, or zero if the error is not a VerificationError
+func verificationReason(err error) VerificationFailureReason {
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return verr.Reason
+	}
+
+	return 0
+}
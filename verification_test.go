@@ -0,0 +1,188 @@
+package bot_lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/bottest"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithVerificationFailureHandler(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	var reason error
+	var headers map[string]string
+	e := New(publicKey,
+		WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		WithVerificationFailureHandler(func(ctx context.Context, h map[string]string, r error) {
+			headers, reason = h, r
+		}),
+	)
+
+	// when a request is received with a bad signature
+	res, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:    http.MethodPost,
+				SourceIP:  "192.0.2.1",
+				UserAgent: "probe/1.0",
+			},
+		},
+		Headers: map[string]string{
+			"x-signature-ed25519":   hex.EncodeToString(make([]byte, ed25519.SignatureSize)),
+			"x-signature-timestamp": "1700000000",
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// then the handler should be called with the reason
+	assert.EqualError(t, reason, "invalid signature")
+	assert.Equal(t, ReasonInvalidSignature, verificationReason(reason))
+	assert.Equal(t, "1700000000", headers["x-signature-timestamp"])
+
+	// and a structured audit record should be logged
+	r := findRecord(logRecords(t, buf), "Failed to verify signature")
+	require.NotNil(t, r)
+	assert.Equal(t, "invalid signature", r["error"])
+	assert.Equal(t, "invalid signature", r["reason"])
+	assert.Equal(t, "192.0.2.1", r["source_ip"])
+	assert.Equal(t, "probe/1.0", r["user_agent"])
+	assert.Equal(t, "1700000000", r["signature_timestamp"])
+}
+
+func TestWithRequireVerification(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := New(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithRequireVerification(true))
+
+	// then the misconfiguration should be logged when the endpoint is created
+	require.NotNil(t, findRecord(logRecords(t, buf), "Verification is required but no public key is configured, all requests will fail"))
+
+	// and requests should fail
+	_, err := e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: string(mustMarshal(t, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionPing}})),
+	})
+	assert.ErrorIs(t, err, ErrNoPublicKey)
+}
+
+func TestWithRequireVerification_PublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	e := New(publicKey, WithLogger(slogt.New(t)), WithRequireVerification(true))
+
+	// when an unsigned request is received then it is rejected as unauthorized
+	res := send(t, e, &discordgo.Interaction{Type: discordgo.InteractionPing})
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestVerify_SignedRequest(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	e := New(publicKey, WithLogger(slogt.New(t)))
+	body := []byte(`{"type":1}`)
+
+	// when a request is signed with the test helper
+	headers := bottest.SignRequest(privateKey, "", body)
+
+	// then it should pass verification
+	assert.NoError(t, e.verify(context.Background(), headers, body))
+
+	// and fail verification if the body is changed
+	assert.Error(t, e.verify(context.Background(), headers, []byte(`{"type":2}`)))
+}
+
+func TestIsVerified(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		publicKey ed25519.PublicKey
+		want      bool
+	}{
+		{name: "public key", publicKey: publicKey, want: true},
+		{name: "no public key", publicKey: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified *bool
+			e := New(tt.publicKey, WithLogger(slogt.New(t))).WithChatApplicationCommand("foo", func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) (err error) {
+				v := IsVerified(ctx)
+				verified = &v
+				return nil
+			})
+
+			res := sendSigned(t, e, privateKey, time.Now(), fooCommand())
+
+			assert.Equal(t, http.StatusAccepted, res.StatusCode)
+			require.NotNil(t, verified)
+			assert.Equal(t, tt.want, *verified)
+		})
+	}
+}
+
+func TestVerify_Reason(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	body := []byte(`{"type":1}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := bottest.SignRequest(privateKey, ts, body)
+	stale := bottest.SignRequest(privateKey, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10), body)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		reason  VerificationFailureReason
+	}{
+		{name: "missing signature", headers: map[string]string{bottest.HeaderTimestamp: ts}, reason: ReasonMissingSignature},
+		{name: "missing timestamp", headers: map[string]string{bottest.HeaderSignature: signed[bottest.HeaderSignature]}, reason: ReasonMissingTimestamp},
+		{name: "bad hex", headers: map[string]string{bottest.HeaderSignature: "not hex", bottest.HeaderTimestamp: ts}, reason: ReasonBadEncoding},
+		{name: "invalid signature", headers: map[string]string{bottest.HeaderSignature: signed[bottest.HeaderSignature], bottest.HeaderTimestamp: "1"}, reason: ReasonInvalidSignature},
+		{name: "stale timestamp", headers: stale, reason: ReasonStaleTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(publicKey, WithLogger(slogt.New(t)), WithMaxTimestampAge(time.Minute))
+
+			err := e.verify(context.Background(), tt.headers, body)
+
+			var verr *VerificationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.reason, verr.Reason)
+		})
+	}
+
+	t.Run("replayed", func(t *testing.T) {
+		e := New(publicKey, WithLogger(slogt.New(t)), WithReplayProtection(NewMemoryNonceStore()))
+		require.NoError(t, e.verify(context.Background(), signed, body))
+
+		err := e.verify(context.Background(), signed, body)
+
+		var verr *VerificationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, ReasonReplayed, verr.Reason)
+	})
+}
@@ -0,0 +1,39 @@
+package bot_lambda
+
+import (
+	"context"
+)
+
+// Go runs f in a goroutine which is tracked by the endpoint, so that Wait can block until it completes. The context
+// passed to f is not cancelled when ctx is, allowing work to continue after the interaction has been responded to.
+//
+// Note that the Lambda execution environment may be frozen as soon as the invocation returns, pausing goroutines until
+// the next invocation (or losing them if the environment is shut down). See Wait.
+func (e *Endpoint) Go(ctx context.Context, f func(ctx context.Context)) {
+	e.inflight.Add(1)
+	go func() {
+		defer e.inflight.Done()
+		f(context.WithoutCancel(ctx))
+	}()
+}
+
+// Wait blocks until the work started with Go has completed, or until ctx is done, in which case the context's error is
+// returned.
+//
+// Waiting before returning from an invocation guarantees that the work completes, but delays the invocation's response
+// and is billed as part of its duration. For Function URLs and API Gateway the response is not sent to Discord until
+// the invocation returns, so Wait should only be called once the response is no longer needed, e.g. from HandleSQS.
+func (e *Endpoint) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		e.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+package bot_lambda
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWait(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	// given async work which is in progress
+	release := make(chan struct{})
+	var completed bool
+	e.Go(context.Background(), func(ctx context.Context) {
+		<-release
+		completed = true
+	})
+
+	waited := make(chan error)
+	go func() { waited <- e.Wait(context.Background()) }()
+
+	// then Wait should block until the work completes
+	select {
+	case <-waited:
+		t.Fatal("Wait returned before async work completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-waited)
+	assert.True(t, completed)
+}
+
+func TestWait_Deadline(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	release := make(chan struct{})
+	defer close(release)
+	e.Go(context.Background(), func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestGo_ContextNotCancelled(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var err error
+	e.Go(ctx, func(ctx context.Context) { err = ctx.Err() })
+
+	assert.NoError(t, e.Wait(context.Background()))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,27 @@
+package bot_lambda
+
+import (
+	"context"
+	"fmt"
+)
+
+// Warmup eagerly resolves a session from the session provider, if one is configured, so that the cost of fetching the
+// token (e.g. from Parameter Store) is paid during Lambda init or a SnapStart hook rather than by the first interaction.
+// The session is only reused by interactions if the provider caches it, e.g. by wrapping it with
+// sessionprovider.CachedFor. Warmup is safe to call multiple times.
+func (e *Endpoint) Warmup(ctx context.Context) (err error) {
+	ctx, s := e.startSpan(ctx, "warmup")
+	defer func() { s.End(err) }()
+
+	if e.s == nil || e.dryRun != nil {
+		return nil
+	}
+
+	if _, err = e.provideSession(ctx); err != nil {
+		return fmt.Errorf("warm up session provider: %w", err)
+	}
+
+	e.logger(ctx).Debug("Warmed up session provider")
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot-lambda/sessionprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	provided := 0
+	var got *discordgo.Session
+	e, calls := commandEndpoint(t, WithMiddleware(func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponse, error) {
+			got = s
+			return next(ctx, s, i)
+		}
+	}))
+	e.WithSessionProvider(sessionprovider.CachedFor(func(ctx context.Context) (*discordgo.Session, error) {
+		provided++
+		return &discordgo.Session{Token: fmt.Sprintf("Bot %d", provided)}, nil
+	}, time.Minute))
+
+	// when the endpoint is warmed up repeatedly
+	require.NoError(t, e.Warmup(context.Background()))
+	require.NoError(t, e.Warmup(context.Background()))
+
+	// then the provider should have been invoked
+	assert.Equal(t, 1, provided)
+
+	// and interactions should reuse the warmed session
+	send(t, e, fooCommand())
+	send(t, e, fooCommand())
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, 1, provided)
+	require.NotNil(t, got)
+	assert.Equal(t, "Bot 1", got.Token)
+}
+
+func TestWarmup_Error(t *testing.T) {
+	e, _ := commandEndpoint(t)
+	e.WithSessionProvider(func(ctx context.Context) (*discordgo.Session, error) {
+		return nil, errors.New("oops")
+	})
+
+	assert.ErrorContains(t, e.Warmup(context.Background()), "oops")
+}
+
+func TestWarmup_NoProvider(t *testing.T) {
+	e, _ := commandEndpoint(t)
+
+	assert.NoError(t, e.Warmup(context.Background()))
+}
@@ -0,0 +1,82 @@
+package bot_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// WebhookEventType is the type of webhook event payload.
+// See https://discord.com/developers/docs/events/webhook-events#webhook-event-payloads
+type WebhookEventType int
+
+const (
+	// WebhookEventPing is sent by Discord to check the endpoint is active
+	WebhookEventPing WebhookEventType = 0
+	// WebhookEventEvent contains an event body
+	WebhookEventEvent WebhookEventType = 1
+)
+
+// WebhookEvent is an application webhook event, delivered to the same endpoint as interactions.
+type WebhookEvent struct {
+	Version       int               `json:"version"`
+	ApplicationID string            `json:"application_id"`
+	Type          WebhookEventType  `json:"type"`
+	Event         *WebhookEventBody `json:"event,omitempty"`
+}
+
+// WebhookEventBody is the body of a webhook event, such as APPLICATION_AUTHORIZED or ENTITLEMENT_CREATE.
+// See https://discord.com/developers/docs/events/webhook-events#event-body-object
+type WebhookEventBody struct {
+	Type      string          `json:"type"`
+	Timestamp string          `json:"timestamp"` // ISO8601 timestamp, which may not include a timezone
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// WebhookEventHandler handles webhook events. Returning an error results in the event being redelivered.
+type WebhookEventHandler func(ctx context.Context, event *WebhookEvent) error
+
+// WithWebhookEventHandler handles application webhook events received by the endpoint. Webhook event PINGs are
+// acknowledged without calling the handler. Events received without a handler are acknowledged and discarded.
+func WithWebhookEventHandler(h WebhookEventHandler) Option {
+	return func(endpoint *Endpoint) {
+		endpoint.webhookEventHandler = h
+	}
+}
+
+// isWebhookEvent returns true if the payload is a webhook event rather than an interaction. Webhook event PINGs have
+// a type of 0, which is not a valid interaction type, and events are the only payloads with an event body.
+func (env envelope) isWebhookEvent() bool {
+	return (env.Type != nil && *env.Type == int(WebhookEventPing)) || len(env.Event) > 0
+}
+
+// handleWebhookEvent passes the webhook event to the webhook event handler
+func (e *Endpoint) handleWebhookEvent(ctx context.Context, body []byte) (err error) {
+	ctx, s := e.startSpan(ctx, "handle webhook event")
+	defer func() { s.End(err) }()
+
+	var event *WebhookEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("unmarshal webhook event: %w", err)
+	}
+
+	log := e.logger(ctx).With("webhook_event_type", event.Type)
+	if event.Type == WebhookEventPing {
+		log.Debug("Received webhook event ping")
+		return nil
+	}
+
+	if event.Event != nil {
+		s.SetAttribute("event", event.Event.Type)
+		log = log.With("event", event.Event.Type)
+	}
+
+	if e.webhookEventHandler == nil {
+		log.Warn("Unhandled webhook event")
+		return nil
+	}
+
+	log.Debug("Handling webhook event")
+
+	return e.webhookEventHandler(ctx, event)
+}
@@ -0,0 +1,84 @@
+package bot_lambda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendWebhookEvent(e *Endpoint, body string) (*events.LambdaFunctionURLResponse, error) {
+	return e.HandleRequest(context.Background(), &events.LambdaFunctionURLRequest{
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: body,
+	})
+}
+
+func TestWebhookEvent_Ping(t *testing.T) {
+	calls := 0
+	e := New(nil, WithLogger(slogt.New(t)), WithWebhookEventHandler(func(ctx context.Context, event *WebhookEvent) error {
+		calls++
+		return nil
+	}))
+
+	res, err := sendWebhookEvent(e, `{"version":1,"application_id":"1234","type":0}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Empty(t, res.Body)
+	assert.Equal(t, 0, calls)
+}
+
+func TestWebhookEvent_Event(t *testing.T) {
+	var got *WebhookEvent
+	e := New(nil, WithLogger(slogt.New(t)), WithWebhookEventHandler(func(ctx context.Context, event *WebhookEvent) error {
+		got = event
+		return nil
+	}))
+
+	res, err := sendWebhookEvent(e, `{
+		"version": 1,
+		"application_id": "1234",
+		"type": 1,
+		"event": {
+			"type": "APPLICATION_AUTHORIZED",
+			"timestamp": "2024-10-18T14:42:53.064834",
+			"data": {"integration_type": 1, "scopes": ["applications.commands"]}
+		}
+	}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.NotNil(t, got)
+	assert.Equal(t, WebhookEventEvent, got.Type)
+	assert.Equal(t, "1234", got.ApplicationID)
+	require.NotNil(t, got.Event)
+	assert.Equal(t, "APPLICATION_AUTHORIZED", got.Event.Type)
+	assert.JSONEq(t, `{"integration_type": 1, "scopes": ["applications.commands"]}`, string(got.Event.Data))
+}
+
+func TestWebhookEvent_Error(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)), WithWebhookEventHandler(func(ctx context.Context, event *WebhookEvent) error {
+		return errors.New("oops")
+	}))
+
+	_, err := sendWebhookEvent(e, `{"version":1,"type":1,"event":{"type":"ENTITLEMENT_CREATE","timestamp":"2024-10-18T14:42:53Z"}}`)
+
+	assert.Error(t, err)
+}
+
+func TestWebhookEvent_Unhandled(t *testing.T) {
+	e := New(nil, WithLogger(slogt.New(t)))
+
+	res, err := sendWebhookEvent(e, `{"version":1,"type":1,"event":{"type":"ENTITLEMENT_CREATE","timestamp":"2024-10-18T14:42:53Z"}}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+}